@@ -0,0 +1,130 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/BlocSoc-iitr/selene/config"
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+	ckzg "github.com/crate-crypto/go-kzg-4844"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BlobSidecar carries the blobs, KZG commitments, and KZG proofs an RPC
+// serves alongside a BlobTx payload, so PayloadToBlock can verify that the
+// payload's blob_versioned_hashes actually correspond to the blob data
+// before surfacing it to callers.
+type BlobSidecar struct {
+	Blobs       [][]byte
+	Commitments [][48]byte
+	Proofs      [][48]byte
+}
+
+// KZGVerifier checks a KZG point-evaluation proof for a blob against its
+// commitment. It's an interface so callers can swap in a different KZG
+// backend (e.g. consensys/gnark-crypto) without touching verifyBlobSidecar.
+type KZGVerifier interface {
+	VerifyBlobKZGProof(blob []byte, commitment [48]byte, proof [48]byte) error
+}
+
+// defaultKZGVerifier backs KZGVerifier with go-kzg-4844's trusted-setup
+// context, which is what PayloadToBlock uses when no verifier is supplied.
+type defaultKZGVerifier struct {
+	ctx *ckzg.Context
+}
+
+var defaultKZG = &defaultKZGVerifier{ctx: ckzg.NewContext4096Secure()}
+
+func (v *defaultKZGVerifier) VerifyBlobKZGProof(blob []byte, commitment [48]byte, proof [48]byte) error {
+	var blobArr ckzg.Blob
+	copy(blobArr[:], blob)
+	return v.ctx.VerifyBlobKZGProof(blobArr, ckzg.Commitment(commitment), ckzg.KZGProof(proof))
+}
+
+// verifyBlobSidecar checks that sidecar holds, starting at blobIndex, a
+// matching blob for every blob versioned hash on tx: the blob's commitment
+// must hash to the versioned hash, and its KZG proof must verify against
+// that commitment. This stops a malicious RPC from serving blobs that
+// don't match the transaction they claim to belong to. It returns the
+// verified blobs for tx and the blobIndex to resume at for the next
+// blob-carrying transaction in the payload.
+func verifyBlobSidecar(tx *Transaction, sidecar *BlobSidecar, blobIndex int, verifier KZGVerifier) ([][]byte, int, error) {
+	if verifier == nil {
+		verifier = defaultKZG
+	}
+
+	var verifiedBlobs [][]byte
+
+	for _, versionedHash := range tx.BlobVersionedHashes {
+		if blobIndex >= len(sidecar.Blobs) {
+			return nil, blobIndex, fmt.Errorf("blob sidecar is missing blob %d for tx %s", blobIndex, tx.Hash)
+		}
+
+		blob := sidecar.Blobs[blobIndex]
+		commitment := sidecar.Commitments[blobIndex]
+		proof := sidecar.Proofs[blobIndex]
+
+		computed := kzgCommitmentToVersionedHash(commitment)
+		if computed != versionedHash {
+			return nil, blobIndex, fmt.Errorf("blob %d versioned hash mismatch: expected %x got %x", blobIndex, versionedHash, computed)
+		}
+
+		if err := verifier.VerifyBlobKZGProof(blob, commitment, proof); err != nil {
+			return nil, blobIndex, fmt.Errorf("blob %d failed KZG proof verification: %w", blobIndex, err)
+		}
+
+		verifiedBlobs = append(verifiedBlobs, blob)
+		blobIndex++
+	}
+
+	return verifiedBlobs, blobIndex, nil
+}
+
+// blobSidecarFromRaw converts the raw sidecars the RPC returned into the
+// BlobSidecar shape verifyBlobSidecar expects. verifyBlobSidecar matches
+// blobs to transactions positionally, in ascending blob index order (the
+// order they appear within the block), so raw is sorted by Index first
+// instead of trusting the RPC to have returned them in order. Returns nil
+// for an empty slice, so callers can pass the result straight to
+// PayloadToBlock without a nil check.
+func blobSidecarFromRaw(raw []consensus_core.RawBlobSidecar) *BlobSidecar {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	sorted := consensus_core.SortedRawBlobSidecars(raw)
+
+	sidecar := &BlobSidecar{
+		Blobs:       make([][]byte, len(sorted)),
+		Commitments: make([][48]byte, len(sorted)),
+		Proofs:      make([][48]byte, len(sorted)),
+	}
+	for i, r := range sorted {
+		sidecar.Blobs[i] = r.Blob
+		sidecar.Commitments[i] = r.KZGCommitment
+		sidecar.Proofs[i] = r.KZGProof
+	}
+	return sidecar
+}
+
+// blobsEnabledForPayload reports whether payload's timestamp is on or
+// after the Cancun fork, the same check checkTxTypeEnabled uses to reject
+// a BlobTx seen too early. send_blocks uses this to skip fetching blob
+// sidecars entirely for pre-Deneb payloads, which can't carry blobs and
+// would otherwise pay for a pointless RPC round trip on every slot.
+func blobsEnabledForPayload(cfg *config.Config, payload consensus_core.ExecutionPayload) bool {
+	chainConfig := cfg.Chain.ChainConfig
+	blockNumber := new(big.Int).SetUint64(payload.BlockNumber())
+	return chainConfig.IsCancun(blockNumber, payload.Timestamp())
+}
+
+// kzgCommitmentToVersionedHash computes versioned_hash = 0x01 ||
+// sha256(commitment)[1:], per EIP-4844.
+func kzgCommitmentToVersionedHash(commitment [48]byte) common.Hash {
+	digest := sha256.Sum256(commitment[:])
+	var versionedHash common.Hash
+	versionedHash[0] = 0x01
+	copy(versionedHash[1:], digest[1:])
+	return versionedHash
+}
@@ -0,0 +1,340 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+	"github.com/golang/snappy"
+)
+
+// ChunkKind distinguishes the payload carried by a chain-file chunk, so a
+// cold start can tell a bootstrap apart from a sync-committee update,
+// finality update, or optimistic update while rebuilding LightClientStore.
+type ChunkKind uint8
+
+const (
+	ChunkKindBootstrap ChunkKind = iota
+	ChunkKindUpdate
+	ChunkKindFinalityUpdate
+	ChunkKindOptimisticUpdate
+)
+
+const chainFileMagic uint32 = 0x53454c45 // "SELE"
+const chainFileVersion uint8 = 1
+
+// ChunkHeader precedes every chunk's compressed payload.
+type ChunkHeader struct {
+	Magic     uint32
+	Version   uint8
+	Kind      ChunkKind
+	ComprSize uint32
+	PlainSize uint32
+	Slot      uint64
+}
+
+// ChunkFooter mirrors ChunkHeader after the payload, so Repair and
+// readChunks can cross-check each chunk's kind, sizes and slot against its
+// header and stop at a half-written chunk left by a crash.
+type ChunkFooter struct {
+	Kind      ChunkKind
+	ComprSize uint32
+	PlainSize uint32
+	Slot      uint64
+}
+
+const chunkHeaderSize = 4 + 1 + 1 + 4 + 4 + 8
+const chunkFooterSize = 1 + 4 + 4 + 8
+
+// Chunk is a decoded chain-file record as returned by Iterate.
+type Chunk struct {
+	Kind ChunkKind
+	Slot uint64
+	SSZ  []byte
+}
+
+// ChainFile is an append-only log of every verified update and finalized
+// header, so ConsensusClient can resume a sync without re-fetching
+// MAX_REQUEST_LIGHT_CLIENT_UPDATES from the RPC after a restart.
+type ChainFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenChainFile opens (creating if necessary) the chain file at path,
+// repairing a truncated trailing chunk left by a prior crash.
+func OpenChainFile(path string) (*ChainFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain file: %w", err)
+	}
+
+	cf := &ChainFile{path: path, file: f}
+	if err := cf.Repair(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return cf, nil
+}
+
+func (cf *ChainFile) Close() error {
+	return cf.file.Close()
+}
+
+// Append writes kind's SSZ-marshaled payload for slot as a new chunk,
+// snappy-compressed, bracketed by a matching header and footer.
+func (cf *ChainFile) Append(kind ChunkKind, slot uint64, ssz []byte) error {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	compressed := snappy.Encode(nil, ssz)
+
+	header := ChunkHeader{
+		Magic:     chainFileMagic,
+		Version:   chainFileVersion,
+		Kind:      kind,
+		ComprSize: uint32(len(compressed)),
+		PlainSize: uint32(len(ssz)),
+		Slot:      slot,
+	}
+	footer := ChunkFooter{
+		Kind:      kind,
+		ComprSize: header.ComprSize,
+		PlainSize: header.PlainSize,
+		Slot:      slot,
+	}
+
+	if _, err := cf.file.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek to chain file end: %w", err)
+	}
+
+	w := bufio.NewWriter(cf.file)
+	if err := writeChunkHeader(w, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(compressed); err != nil {
+		return fmt.Errorf("failed to write chain file payload: %w", err)
+	}
+	if err := writeChunkFooter(w, footer); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush chain file: %w", err)
+	}
+	return cf.file.Sync()
+}
+
+// Iterate streams every chunk from fromSlot (inclusive) to EOF on the
+// returned channel, which is already fully populated and closed by the time
+// Iterate returns. The file is read into memory while cf.mu is held (via
+// readChunks), then copied into a channel buffered to exactly that many
+// chunks, so the copy can never block: a caller that abandons the channel
+// partway through (e.g. after reading the N updates it asked for) leaves
+// nothing running in the background, let alone something holding cf.mu or
+// leaking a goroutine.
+func (cf *ChainFile) Iterate(fromSlot uint64) <-chan Chunk {
+	chunks := cf.readChunks(fromSlot)
+
+	out := make(chan Chunk, len(chunks))
+	for _, c := range chunks {
+		out <- c
+	}
+	close(out)
+
+	return out
+}
+
+// readChunks reads every chunk from fromSlot (inclusive) to EOF or the
+// first corrupt chunk, holding cf.mu only for the duration of the read.
+func (cf *ChainFile) readChunks(fromSlot uint64) []Chunk {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	var chunks []Chunk
+
+	if _, err := cf.file.Seek(0, io.SeekStart); err != nil {
+		return chunks
+	}
+	r := bufio.NewReader(cf.file)
+
+	for {
+		header, err := readChunkHeader(r)
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			return chunks
+		}
+
+		payload := make([]byte, header.ComprSize)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return chunks
+		}
+
+		footer, err := readChunkFooter(r)
+		if err != nil || footer.Slot != header.Slot || footer.Kind != header.Kind ||
+			footer.ComprSize != header.ComprSize || footer.PlainSize != header.PlainSize {
+			return chunks
+		}
+
+		if header.Slot < fromSlot {
+			continue
+		}
+
+		ssz, err := snappy.Decode(nil, payload)
+		if err != nil || uint32(len(ssz)) != header.PlainSize {
+			return chunks
+		}
+
+		chunks = append(chunks, Chunk{Kind: header.Kind, Slot: header.Slot, SSZ: ssz})
+	}
+}
+
+// Repair scans forward from the start of the file, validating each chunk's
+// header against its footer, and truncates everything after the last
+// complete chunk (e.g. half-written trailing bytes left by a crash
+// mid-append). It does not scan backwards from EOF; a corrupt chunk in the
+// middle of the file also truncates every chunk after it.
+func (cf *ChainFile) Repair() error {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	validEnd := int64(0)
+	if _, err := cf.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to chain file start: %w", err)
+	}
+	r := bufio.NewReader(cf.file)
+
+	for {
+		header, err := readChunkHeader(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil || header.Magic != chainFileMagic {
+			break
+		}
+
+		payload := make([]byte, header.ComprSize)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+
+		footer, err := readChunkFooter(r)
+		if err != nil || footer.Slot != header.Slot || footer.Kind != header.Kind ||
+			footer.ComprSize != header.ComprSize || footer.PlainSize != header.PlainSize {
+			break
+		}
+
+		validEnd += int64(chunkHeaderSize) + int64(header.ComprSize) + int64(chunkFooterSize)
+	}
+
+	return cf.file.Truncate(validEnd)
+}
+
+// Head returns the slot of the first chunk in the file, if any.
+func (cf *ChainFile) Head() (uint64, bool) {
+	ch, ok := <-cf.Iterate(0)
+	return ch.Slot, ok
+}
+
+// Tail returns the slot of the last chunk in the file, if any.
+func (cf *ChainFile) Tail() (uint64, bool) {
+	var last Chunk
+	found := false
+	for ch := range cf.Iterate(0) {
+		last = ch
+		found = true
+	}
+	return last.Slot, found
+}
+
+func writeChunkHeader(w io.Writer, h ChunkHeader) error {
+	buf := make([]byte, chunkHeaderSize)
+	binary.BigEndian.PutUint32(buf[0:4], h.Magic)
+	buf[4] = h.Version
+	buf[5] = byte(h.Kind)
+	binary.BigEndian.PutUint32(buf[6:10], h.ComprSize)
+	binary.BigEndian.PutUint32(buf[10:14], h.PlainSize)
+	binary.BigEndian.PutUint64(buf[14:22], h.Slot)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readChunkHeader(r io.Reader) (ChunkHeader, error) {
+	buf := make([]byte, chunkHeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return ChunkHeader{}, err
+	}
+	return ChunkHeader{
+		Magic:     binary.BigEndian.Uint32(buf[0:4]),
+		Version:   buf[4],
+		Kind:      ChunkKind(buf[5]),
+		ComprSize: binary.BigEndian.Uint32(buf[6:10]),
+		PlainSize: binary.BigEndian.Uint32(buf[10:14]),
+		Slot:      binary.BigEndian.Uint64(buf[14:22]),
+	}, nil
+}
+
+func writeChunkFooter(w io.Writer, f ChunkFooter) error {
+	buf := make([]byte, chunkFooterSize)
+	buf[0] = byte(f.Kind)
+	binary.BigEndian.PutUint32(buf[1:5], f.ComprSize)
+	binary.BigEndian.PutUint32(buf[5:9], f.PlainSize)
+	binary.BigEndian.PutUint64(buf[9:17], f.Slot)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readChunkFooter(r io.Reader) (ChunkFooter, error) {
+	buf := make([]byte, chunkFooterSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return ChunkFooter{}, err
+	}
+	return ChunkFooter{
+		Kind:      ChunkKind(buf[0]),
+		ComprSize: binary.BigEndian.Uint32(buf[1:5]),
+		PlainSize: binary.BigEndian.Uint32(buf[5:9]),
+		Slot:      binary.BigEndian.Uint64(buf[9:17]),
+	}, nil
+}
+
+// AppendBootstrap records a verified bootstrap checkpoint.
+func (cf *ChainFile) AppendBootstrap(bootstrap *consensus_core.Bootstrap) error {
+	ssz, err := bootstrap.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to marshal bootstrap: %w", err)
+	}
+	return cf.Append(ChunkKindBootstrap, bootstrap.Header.Slot, ssz)
+}
+
+// AppendUpdate records a verified sync-committee update.
+func (cf *ChainFile) AppendUpdate(update *consensus_core.Update) error {
+	ssz, err := update.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+	return cf.Append(ChunkKindUpdate, update.AttestedHeader.Slot, ssz)
+}
+
+// AppendFinalityUpdate records a verified finality update.
+func (cf *ChainFile) AppendFinalityUpdate(update *consensus_core.FinalityUpdate) error {
+	ssz, err := update.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to marshal finality update: %w", err)
+	}
+	return cf.Append(ChunkKindFinalityUpdate, update.FinalizedHeader.Slot, ssz)
+}
+
+// AppendOptimisticUpdate records a verified optimistic update.
+func (cf *ChainFile) AppendOptimisticUpdate(update *consensus_core.OptimisticUpdate) error {
+	ssz, err := update.MarshalSSZ()
+	if err != nil {
+		return fmt.Errorf("failed to marshal optimistic update: %w", err)
+	}
+	return cf.Append(ChunkKindOptimisticUpdate, update.AttestedHeader.Slot, ssz)
+}
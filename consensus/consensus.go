@@ -6,13 +6,14 @@ package consensus
 // uses common for datatypes
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"log"
-	"math"
+	"math/big"
+	"math/bits"
 
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/BlocSoc-iitr/selene/config"
 	"github.com/BlocSoc-iitr/selene/config/checkpoints"
 	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core/merkle"
 	"github.com/BlocSoc-iitr/selene/consensus/rpc"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
@@ -39,10 +41,18 @@ var (
 	ErrInvalidFinalityProof          = errors.New("invalid finality proof")
 	ErrInvalidNextSyncCommitteeProof = errors.New("invalid next sync committee proof")
 	ErrInvalidSignature              = errors.New("invalid signature")
+	ErrTxTypeNotEnabled              = errors.New("transaction type not yet enabled at this payload's timestamp")
+	ErrInvalidWithdrawalsProof       = errors.New("invalid withdrawals proof")
 )
 
 const MAX_REQUEST_LIGHT_CLIENT_UPDATES = 128
 
+// UPDATE_TIMEOUT is one sync-committee period's worth of slots. When the
+// store hasn't been finalized within this many slots of the current slot,
+// process_light_client_store_force_update forcibly advances it using the
+// best update seen for the period, per the Altair "force update" rule.
+const UPDATE_TIMEOUT = 8192
+
 type GenericUpdate struct {
 	AttestedHeader          consensus_core.Header
 	SyncAggregate           consensus_core.SyncAggregate
@@ -51,6 +61,8 @@ type GenericUpdate struct {
 	NextSyncCommitteeBranch *[]consensus_core.Bytes32
 	FinalizedHeader         consensus_core.Header
 	FinalityBranch          []consensus_core.Bytes32
+	WithdrawalsRoot         *consensus_core.Bytes32
+	WithdrawalsBranch       *[]consensus_core.Bytes32
 }
 
 type ConsensusClient struct {
@@ -69,6 +81,13 @@ type Inner struct {
 	finalizedBlockSend chan *common.Block
 	checkpointSend     chan *[]byte
 	Config             *config.Config
+	gossipFinality     <-chan consensus_core.FinalityUpdate
+	gossipOptimistic   <-chan consensus_core.OptimisticUpdate
+	ChainFile          *ChainFile
+	// lastBlock is the most recently sent head block, kept so send_blocks
+	// can verify its child's excess_blob_gas against it and so BlobBaseFee
+	// has a head to derive from.
+	lastBlock *Block
 }
 type LightClientStore struct {
 	FinalizedHeader               consensus_core.Header
@@ -77,6 +96,12 @@ type LightClientStore struct {
 	OptimisticHeader              consensus_core.Header
 	PreviousMaxActiveParticipants uint64
 	CurrentMaxActiveParticipants  uint64
+	BestValidUpdate               *GenericUpdate
+	// CurrentSyncCommitteeBranch is the Merkle branch proving
+	// CurrentSyncCommitee against FinalizedHeader, retained so
+	// LightClientServer can serve bootstrap responses without
+	// re-deriving it from an upstream RPC.
+	CurrentSyncCommitteeBranch []consensus_core.Bytes32
 }
 
 type Forks struct {
@@ -109,6 +134,15 @@ func (con ConsensusClient) New(rpc *string, config config.Config) ConsensusClien
 	In := &Inner{}
 	inner := In.New(*rpc, blockSend, finalizedBlockSend, checkpointSend, &config)
 
+	if config.ServeLightClient != nil {
+		server := NewLightClientServer(&inner.Store, inner.ChainFile)
+		go func() {
+			if err := server.ListenAndServe(*config.ServeLightClient); err != nil {
+				log.Printf("light client server stopped: %v", err)
+			}
+		}()
+	}
+
 	go func() {
 		err := inner.sync(initialCheckpoint)
 		if err != nil {
@@ -132,7 +166,10 @@ func (con ConsensusClient) New(rpc *string, config config.Config) ConsensusClien
 
 		_ = inner.send_blocks()
 
+		inner.start_gossip(context.Background())
+
 		for {
+			inner.process_light_client_store_force_update()
 			time.Sleep(inner.duration_until_next_update())
 
 			err := inner.advance()
@@ -212,8 +249,45 @@ func sync_all_fallback(inner *Inner, chainID uint64) error {
 	return nil
 }
 
+// newConsensusRpc builds the base ConsensusRpc for rpcURL, fanning out to
+// every URL in config.ConsensusRpcUrls through a quorum-checked
+// MultiConsensusRpc when more than one endpoint is configured, instead of
+// trusting a single beacon API. rpcURL is used as-is when no additional
+// endpoints are configured.
+func newConsensusRpc(rpcURL string, config *config.Config) rpc.ConsensusRpc {
+	if config == nil || len(config.ConsensusRpcUrls) == 0 {
+		return rpc.NewConsensusRpc(rpcURL)
+	}
+
+	endpoints := make([]rpc.ConsensusRpc, len(config.ConsensusRpcUrls))
+	for i, url := range config.ConsensusRpcUrls {
+		endpoints[i] = rpc.NewConsensusRpc(url)
+	}
+
+	multi, err := rpc.NewMultiConsensusRpc(endpoints, 0)
+	if err != nil {
+		log.Printf("failed to build multi-endpoint consensus RPC, falling back to single endpoint: %v", err)
+		return rpc.NewConsensusRpc(rpcURL)
+	}
+	return multi
+}
+
 func (in *Inner) New(rpcURL string, blockSend chan common.Block, finalizedBlockSend chan *common.Block, checkpointSend chan *[]byte, config *config.Config) *Inner {
-	rpcClient := rpc.NewConsensusRpc(rpcURL)
+	rpcClient := newConsensusRpc(rpcURL, config)
+
+	if config != nil && config.Gossip {
+		rpcClient = withGossip(rpcClient, config)
+	}
+
+	var chainFile *ChainFile
+	if config != nil && config.DataDir != "" {
+		cf, err := OpenChainFile(filepath.Join(config.DataDir, "chain.dat"))
+		if err != nil {
+			log.Printf("failed to open chain file, continuing without persistent replay: %v", err)
+		} else {
+			chainFile = cf
+		}
+	}
 
 	return &Inner{
 		RPC:                rpcClient,
@@ -223,9 +297,29 @@ func (in *Inner) New(rpcURL string, blockSend chan common.Block, finalizedBlockS
 		finalizedBlockSend: finalizedBlockSend,
 		checkpointSend:     checkpointSend,
 		Config:             config,
+		ChainFile:          chainFile,
 	}
 
 }
+
+// withGossip wraps inner in a Libp2pGossipRpc joining the light-client
+// gossipsub topics for the network's current fork digest, so start_gossip's
+// type assertion against rpc.GossipConsensusRpc actually succeeds instead of
+// silently falling back to HTTP polling. If the libp2p host fails to start,
+// inner is returned unwrapped and gossip is skipped for this run.
+func withGossip(inner rpc.ConsensusRpc, config *config.Config) rpc.ConsensusRpc {
+	currentSlot := (uint64(time.Now().Unix()) - config.Chain.GenesisTime) / 12
+	forkVersion := consensus_core.CalculateForkVersion(config.Forks, currentSlot)
+	forkDigest := consensus_core.ComputeForkDigest(forkVersion, consensus_core.Bytes32(config.Chain.GenesisRoot))
+
+	gossipRPC, err := rpc.NewLibp2pGossipRpc(context.Background(), inner, forkDigest)
+	if err != nil {
+		log.Printf("failed to start gossip client, falling back to HTTP polling: %v", err)
+		return inner
+	}
+	return gossipRPC
+}
+
 func (in *Inner) Get_rpc() error {
 	chainID, err := in.RPC.ChainId()
 	if err != nil {
@@ -323,6 +417,93 @@ func (in *Inner) get_payloads(ctx context.Context, startSlot, endSlot uint64) ([
 		}
 	}
 }
+
+// start_gossip subscribes to the beacon gossipsub light-client topics when
+// the configured RPC supports it, and applies finality/optimistic updates
+// as soon as they arrive instead of waiting for duration_until_next_update
+// to elapse. The HTTP polling path in advance() stays in place as a
+// fallback for when gossip is unavailable or falls behind.
+func (in *Inner) start_gossip(ctx context.Context) {
+	gossipRPC, ok := in.RPC.(rpc.GossipConsensusRpc)
+	if !ok {
+		return
+	}
+
+	finalityUpdates, optimisticUpdates, err := gossipRPC.SubscribeLightClientUpdates(ctx)
+	if err != nil {
+		log.Printf("gossip subscription failed, falling back to polling: %v", err)
+		return
+	}
+	in.gossipFinality = finalityUpdates
+	in.gossipOptimistic = optimisticUpdates
+
+	go func() {
+		for {
+			select {
+			case update, ok := <-in.gossipFinality:
+				if !ok {
+					return
+				}
+				if err := in.verify_finality_update(&update); err != nil {
+					log.Printf("gossip finality update rejected: %v", err)
+					continue
+				}
+				in.apply_finality_update(&update)
+				_ = in.send_blocks()
+			case update, ok := <-in.gossipOptimistic:
+				if !ok {
+					return
+				}
+				if err := in.verify_optimistic_update(&update); err != nil {
+					log.Printf("gossip optimistic update rejected: %v", err)
+					continue
+				}
+				in.apply_gossip_optimistic_update(&update)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// apply_gossip_optimistic_update applies an optimistic header received over
+// gossip and, unlike the HTTP polling path, surfaces it on blockSend as soon
+// as it's applied rather than waiting for advance()'s next tick. This is
+// what actually cuts update latency versus polling: by the time an update
+// is finalized it is already several slots stale. It does not relax the
+// safety-threshold majority that apply_generic_update already gates
+// store.OptimisticHeader updates on; send_blocks itself refuses to surface
+// a head whose execution payload doesn't resolve (e.g. a parent without
+// execution enabled), via check_execution_payload.
+func (in *Inner) apply_gossip_optimistic_update(update *consensus_core.OptimisticUpdate) {
+	genUpdate := GenericUpdate{
+		AttestedHeader: update.AttestedHeader,
+		SyncAggregate:  update.SyncAggregate,
+		SignatureSlot:  update.SignatureSlot,
+	}
+
+	committeeBits := getBits(update.SyncAggregate.Sync_committee_bits)
+	hadHigherSlot := update.AttestedHeader.Slot > in.Store.OptimisticHeader.Slot
+
+	checkpoint := in.apply_generic_update(&in.Store, &genUpdate)
+	if checkpoint != nil {
+		in.lastCheckpoint = checkpoint
+	}
+
+	// apply_generic_update only promotes store.OptimisticHeader once
+	// committeeBits clears the safety threshold. Mirror that same gate here
+	// so a single-signer gossip update can't short-circuit straight to
+	// blockSend, and confirm the promotion actually landed before sending.
+	updated := hadHigherSlot && committeeBits > in.safety_threshold() &&
+		in.Store.OptimisticHeader.Slot == update.AttestedHeader.Slot
+
+	if updated {
+		if err := in.send_blocks(); err != nil {
+			log.Printf("failed to send gossip-optimistic block: %v", err)
+		}
+	}
+}
+
 func (in *Inner) advance() error {
 	// Fetch and apply finality update
 	finalityUpdate, err := in.RPC.GetFinalityUpdate()
@@ -376,8 +557,12 @@ func (in *Inner) sync(checkpoint [32]byte) error {
 	// Calculate the current sync period
 	currentPeriod := calculate_sync_period(in.Store.FinalizedHeader.Slot)
 
+	// Replay locally persisted updates before hitting the RPC, so a
+	// restart doesn't have to re-fetch MAX_REQUEST_LIGHT_CLIENT_UPDATES.
+	replayedThrough := in.replay_chain_file()
+
 	// Fetch updates
-	updates, err := in.RPC.GetUpdates(currentPeriod, MAX_REQUEST_LIGHT_CLIENT_UPDATES)
+	updates, err := in.RPC.GetUpdates(max(currentPeriod, replayedThrough), MAX_REQUEST_LIGHT_CLIENT_UPDATES)
 	if err != nil {
 		return err
 	}
@@ -415,6 +600,46 @@ func (in *Inner) sync(checkpoint [32]byte) error {
 
 	return nil
 }
+
+// replay_chain_file rebuilds LightClientStore from the on-disk ChainFile,
+// if one is configured, and returns the sync period it replayed through
+// (or the store's current period if there's no chain file or it's empty).
+func (in *Inner) replay_chain_file() uint64 {
+	period := calculate_sync_period(in.Store.FinalizedHeader.Slot)
+	if in.ChainFile == nil {
+		return period
+	}
+
+	for chunk := range in.ChainFile.Iterate(0) {
+		switch chunk.Kind {
+		case ChunkKindUpdate:
+			var update consensus_core.Update
+			if err := update.UnmarshalSSZ(chunk.SSZ); err != nil {
+				log.Printf("skipping corrupt chain-file update: %v", err)
+				continue
+			}
+			in.apply_update(&update)
+		case ChunkKindFinalityUpdate:
+			var update consensus_core.FinalityUpdate
+			if err := update.UnmarshalSSZ(chunk.SSZ); err != nil {
+				log.Printf("skipping corrupt chain-file finality update: %v", err)
+				continue
+			}
+			in.apply_finality_update(&update)
+		case ChunkKindOptimisticUpdate:
+			var update consensus_core.OptimisticUpdate
+			if err := update.UnmarshalSSZ(chunk.SSZ); err != nil {
+				log.Printf("skipping corrupt chain-file optimistic update: %v", err)
+				continue
+			}
+			in.apply_optimistic_update(&update)
+		}
+		period = calculate_sync_period(in.Store.FinalizedHeader.Slot)
+	}
+
+	return period
+}
+
 func (in *Inner) send_blocks() error {
 	// Get slot from the optimistic header
 	slot := in.Store.OptimisticHeader.Slot
@@ -430,14 +655,44 @@ func (in *Inner) send_blocks() error {
 		return err
 	}
 
+	var sidecar *BlobSidecar
+	if blobsEnabledForPayload(in.Config, *payload) {
+		sidecar, err = in.fetchBlobSidecar(slot)
+		if err != nil {
+			return err
+		}
+	}
+	block, err := PayloadToBlock(in.Config, *payload, sidecar)
+	if err != nil {
+		return err
+	}
+	if in.lastBlock != nil && block.ParentHash == in.lastBlock.Hash {
+		if err := verifyBlobGasAccounting(in.lastBlock, &block); err != nil {
+			return fmt.Errorf("rejecting head block from RPC: %w", err)
+		}
+	}
+	in.lastBlock = &block
+
+	var finalizedSidecar *BlobSidecar
+	if blobsEnabledForPayload(in.Config, *finalizedPayload) {
+		finalizedSidecar, err = in.fetchBlobSidecar(finalizedSlot)
+		if err != nil {
+			return err
+		}
+	}
+	finalizedBlock, err := PayloadToBlock(in.Config, *finalizedPayload, finalizedSidecar)
+	if err != nil {
+		return err
+	}
+
 	// Send payload converted to block over the BlockSend channel
 	go func() {
-		in.blockSend <- PayloadToBlock(*payload)
+		in.blockSend <- block
 	}()
 
 	// Send finalized payload converted to block over the FinalizedBlockSend channel
 	go func() {
-		in.finalizedBlockSend <- &PayloadToBlock(*finalizedPayload)
+		in.finalizedBlockSend <- &finalizedBlock
 	}()
 
 	// Send checkpoint over the CheckpointSend channel
@@ -448,6 +703,60 @@ func (in *Inner) send_blocks() error {
 	return nil
 }
 
+// fetchBlobSidecar fetches the blob sidecars the RPC holds for slot via
+// its engine_getBlobsV1-style retrieval, so PayloadToBlock can verify a
+// block's blob-carrying transactions against real blob data instead of
+// skipping verification entirely. A genuine RPC error (including a
+// MultiConsensusRpc quorum disagreement, which means the endpoints can't
+// even agree on what the sidecars are) is returned to the caller instead
+// of being swallowed into "no sidecar": failing the sync for this slot is
+// safer than silently accepting a block with unverified blob data. An
+// empty response (no error, zero sidecars) is the ordinary case for a
+// slot with no blob-carrying transactions and yields a nil sidecar.
+func (in *Inner) fetchBlobSidecar(slot uint64) (*BlobSidecar, error) {
+	raw, err := in.RPC.GetBlobSidecars(slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blob sidecars for slot %d: %w", slot, err)
+	}
+	return blobSidecarFromRaw(raw), nil
+}
+
+// process_light_client_store_force_update runs the Altair "force update"
+// recovery: if the store hasn't finalized a new header in over
+// UPDATE_TIMEOUT slots, promote the best update seen for the period even
+// though it never reached the 2/3 participation majority, so the client
+// doesn't get stuck waiting for a supermajority that may never arrive.
+func (in *Inner) process_light_client_store_force_update() {
+	store := &in.Store
+	if in.expected_current_slot()-store.FinalizedHeader.Slot <= UPDATE_TIMEOUT {
+		return
+	}
+
+	if store.BestValidUpdate != nil && store.BestValidUpdate.FinalizedHeader.Slot > store.FinalizedHeader.Slot {
+		update := store.BestValidUpdate
+		storePeriod := calculate_sync_period(store.FinalizedHeader.Slot)
+		updatePeriod := calculate_sync_period(update.FinalizedHeader.Slot)
+
+		if store.NextSyncCommitee == nil {
+			store.NextSyncCommitee = update.NextSyncCommittee
+		} else if updatePeriod == storePeriod+1 {
+			store.CurrentSyncCommitee = *store.NextSyncCommitee
+			store.NextSyncCommitee = update.NextSyncCommittee
+		}
+
+		store.FinalizedHeader = update.FinalizedHeader
+		if store.FinalizedHeader.Slot > store.OptimisticHeader.Slot {
+			store.OptimisticHeader = store.FinalizedHeader
+		}
+
+		log.Printf("force-updated finalized header to slot %d without 2/3 majority", store.FinalizedHeader.Slot)
+	}
+
+	store.PreviousMaxActiveParticipants = 0
+	store.CurrentMaxActiveParticipants = 0
+	store.BestValidUpdate = nil
+}
+
 func (in *Inner) duration_until_next_update() time.Duration {
 	currentSlot := in.expected_current_slot()
 	nextSlot := currentSlot + 1
@@ -479,6 +788,11 @@ func (in *Inner) bootstrap(checkpoint [32]byte) {
 	verify_bootstrap(checkpoint, bootstrap)
 	apply_bootstrap(&in.Store, bootstrap)
 
+	if in.ChainFile != nil {
+		if err := in.ChainFile.AppendBootstrap(&bootstrap); err != nil {
+			log.Printf("failed to persist bootstrap to chain file: %v", err)
+		}
+	}
 }
 func verify_bootstrap(checkpoint [32]byte, bootstrap consensus_core.Bootstrap) {
 	isCommitteValid := isCurrentCommitteeProofValid(&bootstrap.Header, &bootstrap.CurrentSyncCommittee, bootstrap.CurrentSyncCommitteeBranch)
@@ -504,6 +818,7 @@ func apply_bootstrap(store *LightClientStore, bootstrap consensus_core.Bootstrap
 	store.OptimisticHeader = bootstrap.Header
 	store.PreviousMaxActiveParticipants = 0
 	store.CurrentMaxActiveParticipants = 0
+	store.CurrentSyncCommitteeBranch = bootstrap.CurrentSyncCommitteeBranch
 
 }
 
@@ -560,6 +875,12 @@ func (in *Inner) verify_generic_update(update *GenericUpdate, expectedCurrentSlo
 			return ErrInvalidNextSyncCommitteeProof
 		}
 
+		if update.WithdrawalsRoot != nil && update.WithdrawalsBranch != nil {
+			if !isWithdrawalsProofValid(&update.AttestedHeader, *update.WithdrawalsRoot, *update.WithdrawalsBranch) {
+				return ErrInvalidWithdrawalsProof
+			}
+		}
+
 		var syncCommittee *consensus_core.SyncComittee
 		if updateSigPeriod == storePeriod {
 			syncCommittee = &in.Store.CurrentSyncCommitee
@@ -567,15 +888,14 @@ func (in *Inner) verify_generic_update(update *GenericUpdate, expectedCurrentSlo
 			syncCommittee = in.Store.NextSyncCommitee
 		}
 
-		pks, err := consensus_core.GetParticipatingKeys(syncCommittee, update.SyncAggregate.Sync_committee_bits)
+		aggregatePk, _, err := consensus_core.AggregateParticipating(syncCommittee, update.SyncAggregate.Sync_committee_bits)
 		if err != nil {
-			return fmt.Errorf("failed to get participating keys: %w", err)
+			return fmt.Errorf("failed to aggregate participating keys: %w", err)
 		}
 
-		forkVersion := consensus_core.CalculateForkVersion(forks, update.SignatureSlot)
-		forkDataRoot := consensus_core.ComputeForkDataRoot(forkVersion, consensus_core.Bytes32(in.Config.Chain.GenesisRoot))
-
-		if !verify_sync_committee_signature(pks, &update.AttestedHeader, &update.SyncAggregate.Sync_committee_signature, forkDataRoot) {
+		genesisValidatorRoot := consensus_core.Bytes32(in.Config.Chain.GenesisRoot)
+		aggregatePks := []consensus_core.BLSPubKey{aggregatePk}
+		if !verify_sync_committee_signature(aggregatePks, &update.AttestedHeader, &update.SyncAggregate.Sync_committee_signature, forks, update.SignatureSlot, genesisValidatorRoot) {
 			return ErrInvalidSignature
 		}
 
@@ -591,16 +911,20 @@ func (in *Inner) verify_update(update *consensus_core.Update) error {
 		NextSyncCommitteeBranch: &update.NextSyncCommiteeBranch,
 		FinalizedHeader:         update.FinalizedHeader,
 		FinalityBranch:          update.FinalityBranch,
+		WithdrawalsRoot:         update.WithdrawalsRoot,
+		WithdrawalsBranch:       update.WithdrawalsBranch,
 	}
 	return in.verify_generic_update(&genUpdate, in.expected_current_slot(), &in.Store, in.Config.Chain.GenesisRoot, in.Config.Forks)
 }
 func (in *Inner) verify_finality_update(update *consensus_core.FinalityUpdate) error {
 	genUpdate := GenericUpdate{
-		AttestedHeader:  update.AttestedHeader,
-		SyncAggregate:   update.SyncAggregate,
-		SignatureSlot:   update.SignatureSlot,
-		FinalizedHeader: update.FinalizedHeader,
-		FinalityBranch:  update.FinalityBranch,
+		AttestedHeader:    update.AttestedHeader,
+		SyncAggregate:     update.SyncAggregate,
+		SignatureSlot:     update.SignatureSlot,
+		FinalizedHeader:   update.FinalizedHeader,
+		FinalityBranch:    update.FinalityBranch,
+		WithdrawalsRoot:   update.WithdrawalsRoot,
+		WithdrawalsBranch: update.WithdrawalsBranch,
 	}
 	return in.verify_generic_update(&genUpdate, in.expected_current_slot(), &in.Store, in.Config.Chain.GenesisRoot, in.Config.Forks)
 }
@@ -644,6 +968,22 @@ func (in *Inner) apply_generic_update(store *LightClientStore, update *GenericUp
 	hasMajority := committeeBits*3 >= 512*2
 	if !hasMajority {
 		log.Println("skipping block with low vote count")
+
+		// Track the highest-participation update seen for the current
+		// period even though it doesn't meet the 2/3 majority, so
+		// process_light_client_store_force_update has something to apply
+		// if no good update arrives before UPDATE_TIMEOUT.
+		bestBits := uint64(0)
+		if store.BestValidUpdate != nil {
+			bestBits = getBits(store.BestValidUpdate.SyncAggregate.Sync_committee_bits)
+		}
+		if store.BestValidUpdate == nil || committeeBits > bestBits {
+			store.BestValidUpdate = update
+		}
+	} else if updateFinalizedSlot > store.FinalizedHeader.Slot || updateHasFinalizedNextCommittee {
+		// A majority update is applied below and advances the store, so
+		// the candidate tracked for the store's old position is dropped.
+		store.BestValidUpdate = nil
 	}
 
 	updateIsNewer := updateFinalizedSlot > store.FinalizedHeader.Slot
@@ -697,6 +1037,11 @@ func (in *Inner) apply_update(update *consensus_core.Update) {
 	if checkpoint != nil {
 		in.lastCheckpoint = checkpoint
 	}
+	if in.ChainFile != nil {
+		if err := in.ChainFile.AppendUpdate(update); err != nil {
+			log.Printf("failed to persist update to chain file: %v", err)
+		}
+	}
 }
 func (in *Inner) apply_finality_update(update *consensus_core.FinalityUpdate) {
 	genUpdate := GenericUpdate{
@@ -710,6 +1055,11 @@ func (in *Inner) apply_finality_update(update *consensus_core.FinalityUpdate) {
 	if checkpoint != nil {
 		in.lastCheckpoint = checkpoint
 	}
+	if in.ChainFile != nil {
+		if err := in.ChainFile.AppendFinalityUpdate(update); err != nil {
+			log.Printf("failed to persist finality update to chain file: %v", err)
+		}
+	}
 }
 func (in *Inner) apply_optimistic_update(update *consensus_core.OptimisticUpdate) {
 	genUpdate := GenericUpdate{
@@ -721,6 +1071,11 @@ func (in *Inner) apply_optimistic_update(update *consensus_core.OptimisticUpdate
 	if checkpoint != nil {
 		in.lastCheckpoint = checkpoint
 	}
+	if in.ChainFile != nil {
+		if err := in.ChainFile.AppendOptimisticUpdate(update); err != nil {
+			log.Printf("failed to persist optimistic update to chain file: %v", err)
+		}
+	}
 }
 func (in *Inner) log_finality_update(update *consensus_core.FinalityUpdate) {
 	participation := float32(getBits(update.SyncAggregate.Sync_committee_bits)) / 512.0 * 100.0
@@ -767,9 +1122,9 @@ func (in *Inner) has_sync_update(update *GenericUpdate) bool {
 func (in *Inner) safety_threshold() uint64 {
 	return max(in.Store.CurrentMaxActiveParticipants, in.Store.PreviousMaxActiveParticipants) / 2
 }
-func verify_sync_committee_signature(pks []consensus_core.BLSPubKey, attestedHeader *consensus_core.Header, signature *consensus_core.SignatureBytes, forkDataRoot consensus_core.Bytes32) bool {
+func verify_sync_committee_signature(pks []consensus_core.BLSPubKey, attestedHeader *consensus_core.Header, signature *consensus_core.SignatureBytes, forks *consensus_core.Forks, signatureSlot uint64, genesisValidatorRoot consensus_core.Bytes32) bool {
 	headerRoot := attestedHeader.TreeHashRoot()
-	signingRoot := consensus_core.ComputeCommitteeSignRoot(headerRoot, forkDataRoot)
+	signingRoot := consensus_core.ComputeSignRoot(consensus_core.DomainSyncCommittee, forks, signatureSlot, genesisValidatorRoot, headerRoot)
 	return signature.Verify(signingRoot[:], pks)
 }
 func (in *Inner) compute_committee_sign_root(header consensus_core.Bytes32, fork consensus_core.Bytes32) []byte {
@@ -798,52 +1153,144 @@ func (in *Inner) is_valid_checkpoint(blockHashSlot uint64) bool {
 	return uint64(slotAge) < in.Config.MaxCheckpointAge
 }
 
+// Generalized indices of the single-leaf fields proved against a beacon
+// state root, replacing the old hardcoded (depth, index) pairs. These are
+// stable across Altair/Bellatrix/Capella/Deneb since they index into
+// BeaconState fields whose positions don't change between forks (105
+// reaches one level further, into finalized_checkpoint's Checkpoint.root),
+// not into fields that moved between forks (e.g.
+// latest_execution_payload_header).
+const (
+	gIndexFinalizedHeader      = 105
+	gIndexCurrentSyncCommittee = 54
+	gIndexNextSyncCommittee    = 55
+)
+
 func isFinalityProofValid(attestedHeader *consensus_core.Header, finalizedHeader *consensus_core.Header, finalityBranch []consensus_core.Bytes32) bool {
-	return isProofValid(attestedHeader, finalizedHeader, finalityBranch, 6, 41)
+	return isProofValid(attestedHeader, finalizedHeader, finalityBranch, gIndexFinalizedHeader)
 }
 
 func isCurrentCommitteeProofValid(attestedHeader *consensus_core.Header, currentCommittee *consensus_core.SyncComittee, currentCommitteeBranch []consensus_core.Bytes32) bool {
-	return isProofValid(attestedHeader, currentCommittee, currentCommitteeBranch, 5, 22)
+	return isCommitteeProofValid(attestedHeader, currentCommittee, currentCommitteeBranch, gIndexCurrentSyncCommittee)
 }
 
 func isNextCommitteeProofValid(attestedHeader *consensus_core.Header, currentCommittee *consensus_core.SyncComittee, currentCommitteeBranch []consensus_core.Bytes32) bool {
-	return isProofValid(attestedHeader, currentCommittee, currentCommitteeBranch, 5, 23)
+	return isCommitteeProofValid(attestedHeader, currentCommittee, currentCommitteeBranch, gIndexNextSyncCommittee)
 }
 
-func isProofValid[T TreeHashRoot](attestedHeader *consensus_core.Header, leafObject T, branch []consensus_core.Bytes32, depth, index uint64) bool {
-	// Check if the branch length matches the expected depth
-	if len(branch) != int(depth) {
+// isCommitteeProofValid is isProofValid specialized for SyncComittee leaves,
+// using the committee's memoized Root() instead of re-merkleizing it: the
+// same committee is re-checked against every update in a sync period, so
+// caching its root is where that cost actually gets paid down.
+func isCommitteeProofValid(attestedHeader *consensus_core.Header, committee *consensus_core.SyncComittee, branch []consensus_core.Bytes32, generalizedIndex uint64) bool {
+	if len(branch) != bits.Len64(generalizedIndex)-1 {
 		return false
 	}
 
-	// Get the tree hash root of the leaf object
-	derivedRoot := leafObject.TreeHashRoot()
-	hasher := sha256.New()
+	branchBytes := make([]merkle.Bytes32, len(branch))
+	copy(branchBytes, branch)
 
-	// Iterate through the branch to derive the root
-	for i, node := range branch {
-		if (index/uint64(math.Pow(2, float64(i))))%2 != 0 {
-			hasher.Write(node[:])        // node comes first if index is odd
-			hasher.Write(derivedRoot[:]) // then the derived root
-		} else {
-			hasher.Write(derivedRoot[:]) // derived root comes first if index is even
-			hasher.Write(node[:])        // then the node
-		}
+	return merkle.VerifyMerkleMultiproof(
+		attestedHeader.State_root,
+		[]merkle.Bytes32{committee.Root()},
+		[]uint64{generalizedIndex},
+		branchBytes,
+	)
+}
+
+// isProofValid checks a single-leaf SSZ Merkle proof against the attested
+// header's state root, via the generalized multiproof verifier in the
+// merkle package (VerifyMerkleMultiproof with one leaf degenerates to a
+// single-branch proof).
+func isProofValid[T TreeHashRoot](attestedHeader *consensus_core.Header, leafObject T, branch []consensus_core.Bytes32, generalizedIndex uint64) bool {
+	if len(branch) != bits.Len64(generalizedIndex)-1 {
+		return false
+	}
+
+	branchBytes := make([]merkle.Bytes32, len(branch))
+	copy(branchBytes, branch)
+
+	return merkle.VerifyMerkleMultiproof(
+		attestedHeader.State_root,
+		[]merkle.Bytes32{leafObject.TreeHashRoot()},
+		[]uint64{generalizedIndex},
+		branchBytes,
+	)
+}
+
+// EIP-4844 blob gas accounting constants (Deneb).
+const (
+	targetBlobGasPerBlock     = 393216 // 3 target blobs/block * 131072 gas/blob
+	minBlobBaseFee            = 1
+	blobBaseFeeUpdateFraction = 3338477
+)
+
+// verifyBlobGasAccounting recomputes current's excess_blob_gas from parent
+// and rejects current if the RPC-supplied value disagrees, so a lying RPC
+// can't inject an arbitrary blob base fee by forging ExcessBlobGas.
+func verifyBlobGasAccounting(parent, current *Block) error {
+	expected := int64(parent.ExcessBlobGas) + int64(parent.BlobGasUsed) - targetBlobGasPerBlock
+	if expected < 0 {
+		expected = 0
+	}
 
-		// Reset the derived root with the updated hash
-		derivedRoot = sha256.Sum256(hasher.Sum(nil))
-		hasher.Reset() // Reset hasher for the next iteration
+	if current.ExcessBlobGas != uint64(expected) {
+		return fmt.Errorf("excess blob gas mismatch: expected %d, got %d", expected, current.ExcessBlobGas)
 	}
+	return nil
+}
+
+// blobBaseFee derives the blob base fee a block with the given excess blob
+// gas must charge, via the fake-exponential approximation EIP-4844 uses in
+// place of a true exponential (see fakeExponential).
+func blobBaseFee(excessBlobGas uint64) *big.Int {
+	return fakeExponential(
+		big.NewInt(minBlobBaseFee),
+		new(big.Int).SetUint64(excessBlobGas),
+		big.NewInt(blobBaseFeeUpdateFraction),
+	)
+}
+
+// fakeExponential approximates f * e^(n/d) with the Taylor-series loop
+// EIP-4844 specifies, avoiding floating point while staying within a small
+// integer factor of the true exponential.
+func fakeExponential(f, n, d *big.Int) *big.Int {
+	output := new(big.Int)
+	numerAccum := new(big.Int).Mul(f, d)
+	denom := new(big.Int)
+
+	for i := int64(1); numerAccum.Sign() > 0; i++ {
+		output.Add(output, numerAccum)
+		numerAccum.Mul(numerAccum, n)
+		numerAccum.Div(numerAccum, denom.Mul(d, big.NewInt(i)))
+	}
+
+	return output.Div(output, d)
+}
 
-	// Check if the derived root matches the state root in the attested header
-	return derivedRoot == attestedHeader.State_root
+// BlobBaseFee returns the blob base fee implied by the current head
+// block's excess blob gas, backing an eth_blobBaseFee RPC method once this
+// tree grows an execution-layer RPC surface to serve it from.
+func (in *Inner) BlobBaseFee() *big.Int {
+	if in.lastBlock == nil {
+		return blobBaseFee(0)
+	}
+	return blobBaseFee(in.lastBlock.ExcessBlobGas)
 }
 
-func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
+func PayloadToBlock(cfg *config.Config, value consensus_core.ExecutionPayload, sidecar ...*BlobSidecar) (Block, error) {
 	emptyNonce := "0x0000000000000000"
 	emptyUncleHash := "1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
 
-	var txs []*types.Transaction
+	signer := SignerForPayload(cfg, value)
+
+	var blobSidecar *BlobSidecar
+	if len(sidecar) > 0 {
+		blobSidecar = sidecar[0]
+	}
+	blobIndex := 0
+
+	var txs []*Transaction
 
 	for i, txBytes := range value.Transactions {
 		// Decode the transaction envelope (RLP-encoded)
@@ -852,6 +1299,10 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 			return nil, fmt.Errorf("failed to decode transaction: %v", err)
 		}
 
+		if err := checkTxTypeEnabled(cfg, value, txEnvelope.Type()); err != nil {
+			return Block{}, err
+		}
+
 		tx := Transaction{
 			Hash:             txEnvelope.Hash(),
 			Nonce:            txEnvelope.Nonce(),
@@ -870,7 +1321,6 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 		// Handle transaction types and signatures properly based on Go-Ethereum types
 		switch txEnvelope.Type() {
 		case types.LegacyTxType:
-			signer := types.LatestSignerForChainID(txEnvelope.ChainId())
 			from, err := types.Sender(signer, txEnvelope)
 			if err != nil {
 				return Block{}, fmt.Errorf("failed to recover sender: %v", err)
@@ -888,7 +1338,6 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 			}
 
 		case types.AccessListTxType:
-			signer := types.LatestSignerForChainID(txEnvelope.ChainId())
 			from, err := types.Sender(signer, txEnvelope)
 			if err != nil {
 				return Block{}, fmt.Errorf("failed to recover sender: %v", err)
@@ -907,7 +1356,6 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 			tx.AccessList = txEnvelope.AccessList()
 
 		case types.DynamicFeeTxType:
-			signer := types.LatestSignerForChainID(txEnvelope.ChainId())
 			from, err := types.Sender(signer, txEnvelope)
 			if err != nil {
 				return Block{}, fmt.Errorf("failed to recover sender: %v", err)
@@ -929,7 +1377,6 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 			tx.GasPrice = SomeGasPrice(txEnvelope.MaxFeePerGas(), txEnvelope.MaxPriorityFeePerGas(), value.BaseFeePerGas)
 
 		case types.BlobTxType:
-			signer := types.LatestSignerForChainID(txEnvelope.ChainId())
 			from, err := types.Sender(signer, txEnvelope)
 			if err != nil {
 				return Block{}, fmt.Errorf("failed to recover sender: %v", err)
@@ -951,34 +1398,119 @@ func PayloadToBlock(value consensus_core.ExecutionPayload) (Block, error) {
 			tx.MaxFeePerBlobGas = &txEnvelope.MaxFeePerBlobGas()
 			tx.BlobVersionedHashes = txEnvelope.BlobVersionedHashes()
 			tx.GasPrice = SomeGasPrice(txEnvelope.MaxFeePerGas(), txEnvelope.MaxPriorityFeePerGas(), value.BaseFeePerGas)
+
+			if blobSidecar != nil {
+				verifiedBlobs, newIndex, err := verifyBlobSidecar(&tx, blobSidecar, blobIndex, nil)
+				if err != nil {
+					return Block{}, fmt.Errorf("blob sidecar verification failed: %w", err)
+				}
+				tx.VerifiedBlobs = verifiedBlobs
+				blobIndex = newIndex
+			} else if len(tx.BlobVersionedHashes) > 0 {
+				return Block{}, fmt.Errorf("tx %s carries blob versioned hashes but no blob sidecar was provided to verify them against", tx.Hash)
+			}
+		}
+
+		txs = append(txs, &tx)
+	}
+
+	withdrawals := make([]*Withdrawal, len(value.Withdrawals))
+	for i, w := range value.Withdrawals {
+		withdrawals[i] = &Withdrawal{
+			Index:          w.Index,
+			ValidatorIndex: w.ValidatorIndex,
+			Address:        w.Address,
+			Amount:         w.Amount,
 		}
-		// Construct and return the block
-		return Block{
-			Number:           value.BlockNumber(),
-			BaseFeePerGas:    value.BaseFeePerGas(),
-			Difficulty:       U256Zero(),
-			ExtraData:        value.ExtraData(),
-			GasLimit:         value.GasLimit(),
-			GasUsed:          value.GasUsed(),
-			Hash:             value.BlockHash(),
-			LogsBloom:        value.LogsBloom(),
-			Miner:            value.FeeRecipient(),
-			ParentHash:       value.ParentHash(),
-			ReceiptsRoot:     value.ReceiptsRoot(),
-			StateRoot:        value.StateRoot(),
-			Timestamp:        value.Timestamp(),
-			TotalDifficulty:  U256Zero(),
-			Transactions:     txs,
-			MixHash:          value.PrevRandao(),
-			Nonce:            emptyNonce,
-			Sha3Uncles:       emptyUncleHash,
-			Size:             0,
-			TransactionsRoot: B256Default(),
-			Uncles:           []string{},
-			BlobGasUsed:      value.BlobGasUsed(),
-			ExcessBlobGas:    value.ExcessBlobGas(),
-		}, nil
 	}
+	var withdrawalsRoot *consensus_core.Bytes32
+	if value.Withdrawals != nil {
+		root := withdrawalsSSZRoot(value.Withdrawals)
+		withdrawalsRoot = &root
+	}
+
+	// Construct and return the block
+	return Block{
+		Number:           value.BlockNumber(),
+		BaseFeePerGas:    value.BaseFeePerGas(),
+		Difficulty:       U256Zero(),
+		ExtraData:        value.ExtraData(),
+		GasLimit:         value.GasLimit(),
+		GasUsed:          value.GasUsed(),
+		Hash:             value.BlockHash(),
+		LogsBloom:        value.LogsBloom(),
+		Miner:            value.FeeRecipient(),
+		ParentHash:       value.ParentHash(),
+		ReceiptsRoot:     value.ReceiptsRoot(),
+		StateRoot:        value.StateRoot(),
+		Timestamp:        value.Timestamp(),
+		TotalDifficulty:  U256Zero(),
+		Transactions:     txs,
+		MixHash:          value.PrevRandao(),
+		Nonce:            emptyNonce,
+		Sha3Uncles:       emptyUncleHash,
+		Size:             0,
+		TransactionsRoot: B256Default(),
+		Uncles:           []string{},
+		BlobGasUsed:      value.BlobGasUsed(),
+		ExcessBlobGas:    value.ExcessBlobGas(),
+		Withdrawals:      withdrawals,
+		WithdrawalsRoot:  withdrawalsRoot,
+	}, nil
+}
+
+// Withdrawal mirrors the EIP-4895 beacon-chain withdrawal, as carried by
+// Capella+ execution payloads.
+type Withdrawal struct {
+	Index          uint64
+	ValidatorIndex uint64
+	Address        [20]byte
+	Amount         uint64
+}
+
+// maxWithdrawalsPerPayload is MAX_WITHDRAWALS_PER_PAYLOAD, the SSZ list
+// limit for ExecutionPayload.withdrawals from Capella onward.
+const maxWithdrawalsPerPayload = 16
+
+// withdrawalsSSZRoot computes the SSZ root of a payload's withdrawals as a
+// List[Withdrawal, MAX_WITHDRAWALS_PER_PAYLOAD], so it can be checked
+// against a beacon header's withdrawals_root via isWithdrawalsProofValid.
+// List roots mix the element count into the merkleized chunk root, unlike
+// the fixed-size vector root MerkleizeChunks alone would produce.
+func withdrawalsSSZRoot(withdrawals []consensus_core.Withdrawal) consensus_core.Bytes32 {
+	chunks := make([][32]byte, len(withdrawals))
+	for i, w := range withdrawals {
+		chunks[i] = w.TreeHashRoot()
+	}
+	root := merkle.MerkleizeChunks(chunks, maxWithdrawalsPerPayload)
+	return merkle.Mixin(root, uint64(len(withdrawals)))
+}
+
+// gIndexWithdrawalsRoot is the generalized index of
+// latest_execution_payload_header.withdrawals_root within BeaconState,
+// valid from Capella onward: field 24 of BeaconState's 28 fields
+// (latest_execution_payload_header), concatenated with field 14 of
+// ExecutionPayloadHeader's 15 fields (withdrawals_root). See
+// TestGIndexWithdrawalsRootMatchesContainerLayout, which derives this
+// value from those field counts instead of trusting the literal.
+const gIndexWithdrawalsRoot = 910
+
+// isWithdrawalsProofValid mirrors isFinalityProofValid: it checks that
+// withdrawalsRoot, combined with branch, merkleizes to the attested
+// header's state root, letting light-client updates covering Capella and
+// Deneb headers verify the withdrawals_root field.
+func isWithdrawalsProofValid(attestedHeader *consensus_core.Header, withdrawalsRoot consensus_core.Bytes32, branch []consensus_core.Bytes32) bool {
+	return isProofValid(attestedHeader, withdrawalsRootLeaf{withdrawalsRoot}, branch, gIndexWithdrawalsRoot)
+}
+
+// withdrawalsRootLeaf adapts a plain Bytes32 to the TreeHashRoot
+// constraint used by isProofValid.
+type withdrawalsRootLeaf struct {
+	root consensus_core.Bytes32
+}
+
+func (l withdrawalsRootLeaf) TreeHashRoot() consensus_core.Bytes32 {
+	return l.root
 }
 
 // getBits counts the number of bits set to 1 in a [64]byte array
@@ -0,0 +1,115 @@
+package consensus_core
+
+import (
+	"fmt"
+	"testing"
+)
+
+// buildTestCommittee derives SyncCommitteeSize real BLS keypairs via
+// DeriveBLSKey so AggregateParticipating's tests and benchmarks exercise
+// genuine point arithmetic instead of zero-valued placeholder keys.
+func buildTestCommittee(t testing.TB) *SyncComittee {
+	t.Helper()
+
+	pubKeys := make([]BLSPubKey, SyncCommitteeSize)
+	for i := range pubKeys {
+		ikm := make([]byte, 32)
+		ikm[0] = byte(i)
+		ikm[1] = byte(i >> 8)
+		_, pk, err := DeriveBLSKey(ikm, nil, []byte("aggregate-test"))
+		if err != nil {
+			t.Fatalf("DeriveBLSKey(%d) failed: %v", i, err)
+		}
+		pubKeys[i] = pk
+	}
+
+	aggregate := pubKeys[0]
+	for _, pk := range pubKeys[1:] {
+		aggregate = aggregate.Add(pk)
+	}
+
+	return &SyncComittee{pubkeys: pubKeys, AggregatePubkey: aggregate}
+}
+
+func bitfieldWithParticipation(fraction float64) [64]byte {
+	var bitfield [64]byte
+	want := int(float64(SyncCommitteeSize) * fraction)
+	for i := 0; i < want; i++ {
+		bitfield[i/8] |= 1 << uint(i%8)
+	}
+	return bitfield
+}
+
+func sumParticipatingKeys(committee *SyncComittee, bitfield [64]byte) (BLSPubKey, error) {
+	pks, err := GetParticipatingKeys(committee, bitfield)
+	if err != nil {
+		return BLSPubKey{}, err
+	}
+
+	var aggregate BLSPubKey
+	for i, pk := range pks {
+		if i == 0 {
+			aggregate = pk
+			continue
+		}
+		aggregate = aggregate.Add(pk)
+	}
+	return aggregate, nil
+}
+
+func TestAggregateParticipatingMatchesSummedKeys(t *testing.T) {
+	committee := buildTestCommittee(t)
+
+	for _, fraction := range []float64{0.3, 0.6, 0.9} {
+		bitfield := bitfieldWithParticipation(fraction)
+
+		want, err := sumParticipatingKeys(committee, bitfield)
+		if err != nil {
+			t.Fatalf("sumParticipatingKeys: %v", err)
+		}
+
+		got, participants, err := AggregateParticipating(committee, bitfield)
+		if err != nil {
+			t.Fatalf("AggregateParticipating: %v", err)
+		}
+
+		wantParticipants := int(float64(SyncCommitteeSize) * fraction)
+		if participants != wantParticipants {
+			t.Fatalf("AggregateParticipating participants = %d, want %d", participants, wantParticipants)
+		}
+		if got != want {
+			t.Fatalf("AggregateParticipating result diverges from summed participating keys at %.0f%% participation", fraction*100)
+		}
+	}
+}
+
+// BenchmarkAggregateParticipating and BenchmarkSumParticipatingKeys compare
+// the bitfield-subtract fast path against summing participants from zero,
+// at the dense participation levels (85-100%) seen on mainnet.
+func BenchmarkAggregateParticipating(b *testing.B) {
+	committee := buildTestCommittee(b)
+
+	for _, fraction := range []float64{0.85, 0.95, 1.0} {
+		bitfield := bitfieldWithParticipation(fraction)
+		b.Run(fmt.Sprintf("%.0f%%", fraction*100), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _, _ = AggregateParticipating(committee, bitfield)
+			}
+		})
+	}
+}
+
+func BenchmarkSumParticipatingKeys(b *testing.B) {
+	committee := buildTestCommittee(b)
+
+	for _, fraction := range []float64{0.85, 0.95, 1.0} {
+		bitfield := bitfieldWithParticipation(fraction)
+		b.Run(fmt.Sprintf("%.0f%%", fraction*100), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = sumParticipatingKeys(committee, bitfield)
+			}
+		})
+	}
+}
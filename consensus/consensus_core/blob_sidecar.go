@@ -0,0 +1,26 @@
+package consensus_core
+
+import "sort"
+
+// RawBlobSidecar is the engine_getBlobsV1 / beacon blob_sidecars response
+// shape for a single blob: the blob data plus its KZG commitment and
+// proof, as served by the consensus RPC before a light client has checked
+// it against a block's blob_versioned_hashes.
+type RawBlobSidecar struct {
+	Index         uint64
+	Blob          []byte
+	KZGCommitment [48]byte
+	KZGProof      [48]byte
+}
+
+// SortedRawBlobSidecars returns a copy of raw sorted by Index, so every
+// caller that needs a deterministic order for blob sidecars (matching
+// blobs to transactions positionally, or hashing a response for quorum
+// comparison) does it the same way instead of each reimplementing the
+// sort independently.
+func SortedRawBlobSidecars(raw []RawBlobSidecar) []RawBlobSidecar {
+	sorted := make([]RawBlobSidecar, len(raw))
+	copy(sorted, raw)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+	return sorted
+}
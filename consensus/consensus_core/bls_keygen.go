@@ -0,0 +1,108 @@
+package consensus_core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// BLSPrivKey is a BLS12-381 scalar private key, big-endian encoded.
+type BLSPrivKey [32]byte
+
+// PublicKey derives the BLS12-381 G1 public key sk * G1.
+func (sk BLSPrivKey) PublicKey() (BLSPubKey, error) {
+	secretKey := new(blst.SecretKey)
+	if secretKey.Deserialize(sk[:]) == nil {
+		return BLSPubKey{}, errors.New("invalid BLS private key scalar")
+	}
+
+	point := new(blst.P1Affine).From(secretKey)
+	if point == nil {
+		return BLSPubKey{}, errors.New("failed to derive BLS public key")
+	}
+
+	var pubKey BLSPubKey
+	copy(pubKey[:], point.Compress())
+	return pubKey, nil
+}
+
+// blsCurveOrder is the order r of the BLS12-381 scalar field, as defined
+// by the IETF draft-irtf-cfrg-bls-signature KeyGen algorithm.
+var blsCurveOrder, _ = new(big.Int).SetString(
+	"52435875175126190479447740508185965837690552500527637822603658699938581184513", 10,
+)
+
+// DeriveBLSKey implements the IETF draft-irtf-cfrg-bls-signature KeyGen
+// algorithm: it deterministically derives a BLS12-381 private/public
+// keypair from ikm, salt, and keyInfo. It is used to produce reproducible
+// test vectors (e.g. mock sync committees) without shipping secrets or
+// depending on a live beacon node.
+func DeriveBLSKey(ikm []byte, salt []byte, keyInfo []byte) (BLSPrivKey, BLSPubKey, error) {
+	if len(ikm) < 32 {
+		return BLSPrivKey{}, BLSPubKey{}, errors.New("ikm must be at least 32 bytes")
+	}
+
+	if salt == nil {
+		sum := sha256.Sum256([]byte("BLS-SIG-KEYGEN-SALT-"))
+		salt = sum[:]
+	}
+
+	const l = 48
+	ikmZeroPad := append(append([]byte{}, ikm...), 0x00)
+
+	for {
+		prk := hkdfExtract(salt, ikmZeroPad)
+		okm := hkdfExpand(prk, append(append([]byte{}, keyInfo...), i2osp(l, 2)...), l)
+
+		sk := new(big.Int).Mod(new(big.Int).SetBytes(okm), blsCurveOrder)
+		if sk.Sign() != 0 {
+			var privKey BLSPrivKey
+			skBytes := sk.Bytes()
+			copy(privKey[len(privKey)-len(skBytes):], skBytes)
+
+			pubKey, err := privKey.PublicKey()
+			if err != nil {
+				return BLSPrivKey{}, BLSPubKey{}, err
+			}
+			return privKey, pubKey, nil
+		}
+
+		next := sha256.Sum256(salt)
+		salt = next[:]
+	}
+}
+
+func i2osp(n, length int) []byte {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0 && n > 0; i-- {
+		out[i] = byte(n & 0xff)
+		n >>= 8
+	}
+	return out
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+func hkdfExpand(prk, info []byte, length int) []byte {
+	hashLen := sha256.Size
+	n := (length + hashLen - 1) / hashLen
+
+	okm := make([]byte, 0, n*hashLen)
+	var prev []byte
+	for i := 1; i <= n; i++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{byte(i)})
+		prev = mac.Sum(nil)
+		okm = append(okm, prev...)
+	}
+	return okm[:length]
+}
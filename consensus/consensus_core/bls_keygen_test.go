@@ -0,0 +1,97 @@
+package consensus_core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeriveBLSKeyRejectsShortIKM(t *testing.T) {
+	_, _, err := DeriveBLSKey(make([]byte, 31), nil, nil)
+	if err == nil {
+		t.Fatal("expected error for ikm shorter than 32 bytes")
+	}
+}
+
+func TestDeriveBLSKeyIsDeterministic(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x42}, 32)
+
+	sk1, pk1, err := DeriveBLSKey(ikm, nil, []byte("test"))
+	if err != nil {
+		t.Fatalf("DeriveBLSKey: %v", err)
+	}
+	sk2, pk2, err := DeriveBLSKey(ikm, nil, []byte("test"))
+	if err != nil {
+		t.Fatalf("DeriveBLSKey: %v", err)
+	}
+
+	if sk1 != sk2 {
+		t.Fatal("DeriveBLSKey produced different private keys for identical inputs")
+	}
+	if pk1 != pk2 {
+		t.Fatal("DeriveBLSKey produced different public keys for identical inputs")
+	}
+}
+
+// TestDeriveBLSKeyVariesWithInput checks that ikm, salt, and keyInfo are all
+// actually mixed into the derivation, not silently ignored.
+func TestDeriveBLSKeyVariesWithInput(t *testing.T) {
+	baseIKM := bytes.Repeat([]byte{0x01}, 32)
+	altIKM := bytes.Repeat([]byte{0x02}, 32)
+	altSalt := bytes.Repeat([]byte{0x03}, 32)
+	altKeyInfo := []byte("other-key-info")
+
+	base, _, err := DeriveBLSKey(baseIKM, nil, []byte("key-info"))
+	if err != nil {
+		t.Fatalf("DeriveBLSKey: %v", err)
+	}
+
+	cases := map[string]struct {
+		ikm     []byte
+		salt    []byte
+		keyInfo []byte
+	}{
+		"different ikm":      {altIKM, nil, []byte("key-info")},
+		"different salt":     {baseIKM, altSalt, []byte("key-info")},
+		"different key info": {baseIKM, nil, altKeyInfo},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			sk, _, err := DeriveBLSKey(c.ikm, c.salt, c.keyInfo)
+			if err != nil {
+				t.Fatalf("DeriveBLSKey: %v", err)
+			}
+			if sk == base {
+				t.Fatalf("DeriveBLSKey did not vary output for %s", name)
+			}
+		})
+	}
+}
+
+// TestDeriveBLSKeyProducesValidPoint exercises the one check a memorized
+// hex fixture can't replace: the derived scalar must actually be a valid
+// BLS12-381 private key that blst is willing to turn into a public key,
+// which would fail if KeyGen's mod-reduction or big-endian encoding were
+// wrong.
+func TestDeriveBLSKeyProducesValidPoint(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		ikm := bytes.Repeat([]byte{byte(i + 1)}, 32)
+		sk, pk, err := DeriveBLSKey(ikm, nil, []byte("fixture"))
+		if err != nil {
+			t.Fatalf("DeriveBLSKey(%d): %v", i, err)
+		}
+
+		derivedPk, err := sk.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey() rejected DeriveBLSKey's output as an invalid scalar: %v", err)
+		}
+		if derivedPk != pk {
+			t.Fatalf("DeriveBLSKey's returned public key doesn't match sk.PublicKey()")
+		}
+
+		var zero BLSPrivKey
+		if sk == zero {
+			t.Fatal("DeriveBLSKey returned the zero scalar")
+		}
+	}
+}
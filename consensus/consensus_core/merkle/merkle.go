@@ -0,0 +1,231 @@
+// Package merkle implements the generalized-index SSZ Merkle proof
+// primitives shared by the consensus_core tree-hash-root implementations.
+package merkle
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"sort"
+
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+)
+
+// Bytes32 is an alias for the common consensus_core hash type so callers
+// don't need to import both packages just to spell the type.
+type Bytes32 = consensus_core.Bytes32
+
+// VerifyProof checks that leaf, combined with branch, merkleizes to root
+// under the given generalized index. The bits below the index's leading 1
+// encode the path from the root down to the leaf, most significant bit
+// first, so the proof is walked from the leaf upward starting at bit 0:
+// branch[i] is hashed with the running value, on the left if bit i is 1
+// (the running value was a right child) or on the right if bit i is 0.
+func VerifyProof(root Bytes32, leaf Bytes32, branch [][]byte, generalizedIndex uint64) bool {
+	depth := bits.Len64(generalizedIndex) - 1
+	if depth < 0 || len(branch) != depth {
+		return false
+	}
+
+	value := leaf
+	for i := 0; i < depth; i++ {
+		sibling := branch[i]
+		if (generalizedIndex>>uint(i))&1 == 1 {
+			value = hashPair(sibling, value[:])
+		} else {
+			value = hashPair(value[:], sibling)
+		}
+	}
+
+	return value == root
+}
+
+func hashPair(left, right []byte) Bytes32 {
+	h := sha256.New()
+	h.Write(left)
+	h.Write(right)
+	var out Bytes32
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+// ProofIndex returns the generalized index of field within typ, as defined
+// by the SSZ container layout for the beacon light-client types this
+// package proves against (BeaconBlockHeader, SyncCommittee,
+// ExecutionPayloadHeader). Unknown (typ, field) pairs return 0.
+func ProofIndex(typ, field string) uint64 {
+	switch typ {
+	case "BeaconBlockHeader":
+		switch field {
+		case "state_root":
+			return 11
+		case "body_root":
+			return 12
+		}
+	case "SyncCommittee":
+		switch field {
+		case "pubkeys":
+			return 2
+		case "aggregate_pubkey":
+			return 3
+		}
+	case "ExecutionPayloadHeader":
+		switch field {
+		case "withdrawals_root":
+			return 30
+		case "block_hash":
+			return 28
+		}
+	case "BeaconState":
+		switch field {
+		case "finalized_checkpoint":
+			return 52
+		case "current_sync_committee":
+			return 54
+		case "next_sync_committee":
+			return 55
+		}
+	}
+	return 0
+}
+
+// MerkleizeChunks computes the SSZ merkle root of chunks, padding with
+// zero chunks up to limit (a power of two) when limit is greater than
+// len(chunks). A limit of 0 pads chunks only up to the next power of two
+// of len(chunks).
+func MerkleizeChunks(chunks [][32]byte, limit uint64) Bytes32 {
+	size := uint64(len(chunks))
+	if limit == 0 {
+		limit = nextPowerOfTwo(size)
+	}
+	if size > limit {
+		limit = size
+	}
+	depth := bits.Len64(nextPowerOfTwo(limit) - 1)
+
+	layer := make([]Bytes32, nextPowerOfTwo(limit))
+	for i, c := range chunks {
+		layer[i] = Bytes32(c)
+	}
+
+	for d := 0; d < depth; d++ {
+		next := make([]Bytes32, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i][:], layer[2*i+1][:])
+		}
+		layer = next
+	}
+
+	if len(layer) == 0 {
+		return Bytes32{}
+	}
+	return layer[0]
+}
+
+// Mixin mixes a length value into root, as SSZ does for variable-size
+// list and bitlist roots: mixed_root = hash(root || little_endian(length)).
+func Mixin(root Bytes32, length uint64) Bytes32 {
+	var lengthBytes [32]byte
+	for i := 0; i < 8; i++ {
+		lengthBytes[i] = byte(length >> (8 * i))
+	}
+	return hashPair(root[:], lengthBytes[:])
+}
+
+// VerifyMerkleMultiproof verifies a multi-leaf SSZ Merkle proof in a single
+// bottom-up pass, replacing the old depth/index-hardcoded isProofValid. It
+// sorts leaves by generalized index, seeds an index->hash map with them,
+// then repeatedly pairs each index with its sibling idx^1 (consulting the
+// map before falling back to the next branch element), writes
+// sha256(left||right) at idx/2, and climbs each index until it reaches 1.
+// Leaves at different depths reach 1 in different rounds, so an index that
+// gets there early is set aside rather than climbed past; verification
+// finishes once every index has reached 1 and compares the accumulated
+// root hash against root.
+func VerifyMerkleMultiproof(root Bytes32, leaves []Bytes32, generalizedIndices []uint64, branch []Bytes32) bool {
+	if len(leaves) != len(generalizedIndices) || len(leaves) == 0 {
+		return false
+	}
+
+	order := make([]int, len(leaves))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return generalizedIndices[order[a]] < generalizedIndices[order[b]]
+	})
+
+	nodes := make(map[uint64]Bytes32, len(leaves))
+	indices := make([]uint64, len(leaves))
+	for pos, i := range order {
+		nodes[generalizedIndices[i]] = leaves[i]
+		indices[pos] = generalizedIndices[i]
+	}
+
+	// haveRoot records that some index already climbed to 1 (the tree
+	// root). It's tracked outside of indices/nodes bookkeeping rather than
+	// left in the active set, since leaves at different depths reach 1 in
+	// different rounds: once there, an index must be set aside, not paired
+	// with a fabricated sibling (1^1 == 0) and climbed past index 1.
+	haveRoot := false
+	branchPos := 0
+
+	for len(indices) > 0 {
+		active := indices[:0:0]
+		for _, idx := range indices {
+			if idx == 1 {
+				haveRoot = true
+				continue
+			}
+			active = append(active, idx)
+		}
+		if len(active) == 0 {
+			break
+		}
+
+		next := make([]uint64, 0, len(active))
+		seen := make(map[uint64]bool, len(active))
+
+		for _, idx := range active {
+			parent := idx / 2
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+
+			sibling := idx ^ 1
+			siblingHash, ok := nodes[sibling]
+			if !ok {
+				if branchPos >= len(branch) {
+					return false
+				}
+				siblingHash = branch[branchPos]
+				branchPos++
+			}
+
+			self := nodes[idx]
+			var left, right Bytes32
+			if idx%2 == 0 {
+				left, right = self, siblingHash
+			} else {
+				left, right = siblingHash, self
+			}
+
+			nodes[parent] = hashPair(left[:], right[:])
+			next = append(next, parent)
+		}
+
+		indices = next
+	}
+
+	if !haveRoot {
+		return false
+	}
+	return nodes[1] == root
+}
+
+func nextPowerOfTwo(n uint64) uint64 {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len64(n-1)
+}
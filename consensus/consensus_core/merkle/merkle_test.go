@@ -0,0 +1,426 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/bits"
+	"testing"
+)
+
+// buildTree returns every node of a perfect binary tree over leaves (whose
+// count must be a power of two), keyed by generalized index, so tests can
+// derive a real root and real branch elements instead of hand-computing
+// sha256 output by hex.
+func buildTree(leaves []Bytes32) map[uint64]Bytes32 {
+	n := uint64(len(leaves))
+	nodes := make(map[uint64]Bytes32, 2*n)
+
+	layer := make([]Bytes32, n)
+	copy(layer, leaves)
+
+	base := n
+	for i, l := range layer {
+		nodes[base+uint64(i)] = l
+	}
+
+	for base > 1 {
+		next := make([]Bytes32, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i][:], layer[2*i+1][:])
+		}
+		base /= 2
+		for i, l := range next {
+			nodes[base+uint64(i)] = l
+		}
+		layer = next
+	}
+
+	return nodes
+}
+
+// branchFor walks from gIndex up to the root, collecting each level's
+// sibling from nodes.
+func branchFor(nodes map[uint64]Bytes32, gIndex uint64) []Bytes32 {
+	var branch []Bytes32
+	for idx := gIndex; idx > 1; idx /= 2 {
+		branch = append(branch, nodes[idx^1])
+	}
+	return branch
+}
+
+func leaf(s string) Bytes32 {
+	return sha256.Sum256([]byte(s))
+}
+
+func TestVerifyProof(t *testing.T) {
+	leaves := []Bytes32{leaf("a"), leaf("b"), leaf("c"), leaf("d")}
+	nodes := buildTree(leaves) // indices 4-7 are leaves, 1 is the root
+	root := nodes[1]
+
+	tests := []struct {
+		name             string
+		generalizedIndex uint64
+		leaf             Bytes32
+		branch           [][]byte
+		want             bool
+	}{
+		{
+			name:             "valid proof for first leaf",
+			generalizedIndex: 4,
+			leaf:             leaves[0],
+			branch:           bytesBranch(branchFor(nodes, 4)),
+			want:             true,
+		},
+		{
+			name:             "valid proof for last leaf",
+			generalizedIndex: 7,
+			leaf:             leaves[3],
+			branch:           bytesBranch(branchFor(nodes, 7)),
+			want:             true,
+		},
+		{
+			name:             "wrong leaf value",
+			generalizedIndex: 4,
+			leaf:             leaves[1],
+			branch:           bytesBranch(branchFor(nodes, 4)),
+			want:             false,
+		},
+		{
+			name:             "branch length mismatch",
+			generalizedIndex: 4,
+			leaf:             leaves[0],
+			branch:           bytesBranch(branchFor(nodes, 4))[:1],
+			want:             false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := VerifyProof(root, tt.leaf, tt.branch, tt.generalizedIndex)
+			if got != tt.want {
+				t.Errorf("VerifyProof() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyMerkleMultiproof(t *testing.T) {
+	leaves := []Bytes32{leaf("a"), leaf("b"), leaf("c"), leaf("d"), leaf("e"), leaf("f"), leaf("g"), leaf("h")}
+	nodes := buildTree(leaves) // indices 8-15 are leaves, 1 is the root
+	root := nodes[1]
+
+	t.Run("single leaf degenerates to VerifyProof", func(t *testing.T) {
+		ok := VerifyMerkleMultiproof(root, []Bytes32{leaves[0]}, []uint64{8}, branchFor(nodes, 8))
+		if !ok {
+			t.Fatal("expected single-leaf multiproof to verify")
+		}
+	})
+
+	t.Run("same-depth leaves proved together", func(t *testing.T) {
+		// 8 and 9 share a parent (4), so no branch element is needed until
+		// the climb continues past it; the rest is a single-leaf proof
+		// starting from 4.
+		branch := branchFor(nodes, 4)
+		ok := VerifyMerkleMultiproof(root, []Bytes32{leaves[0], leaves[1]}, []uint64{8, 9}, branch)
+		if !ok {
+			t.Fatal("expected same-depth multiproof to verify")
+		}
+	})
+
+	t.Run("mixed-depth leaves: a depth-1 leaf and a depth-3 leaf", func(t *testing.T) {
+		// Index 2 is the entire left half of the tree treated as one opaque
+		// leaf (depth 1); index 12 is an ordinary leaf three levels down in
+		// the right half (depth 3). Index 2 reaches the root after a single
+		// round while 12 needs three, which is exactly the mismatched-depth
+		// case that used to pair the already-resolved root with a
+		// fabricated sibling (1^1 == 0) instead of setting it aside.
+		opaqueLeft := nodes[2]
+		branch := []Bytes32{nodes[3], nodes[13], nodes[7]}
+		ok := VerifyMerkleMultiproof(root, []Bytes32{opaqueLeft, leaves[4]}, []uint64{2, 12}, branch)
+		if !ok {
+			t.Fatal("expected mixed-depth multiproof to verify")
+		}
+	})
+
+	t.Run("tampered leaf fails", func(t *testing.T) {
+		branch := branchFor(nodes, 4)
+		ok := VerifyMerkleMultiproof(root, []Bytes32{leaves[1], leaves[1]}, []uint64{8, 9}, branch)
+		if ok {
+			t.Fatal("expected tampered multiproof to fail")
+		}
+	})
+
+	t.Run("mismatched leaves and indices fails", func(t *testing.T) {
+		if VerifyMerkleMultiproof(root, []Bytes32{leaves[0]}, []uint64{8, 9}, nil) {
+			t.Fatal("expected length mismatch to fail")
+		}
+	})
+
+	t.Run("empty input fails", func(t *testing.T) {
+		if VerifyMerkleMultiproof(root, nil, nil, nil) {
+			t.Fatal("expected empty input to fail")
+		}
+	})
+}
+
+func TestMerkleizeChunks(t *testing.T) {
+	chunks := [][32]byte{leaf("a"), leaf("b"), leaf("c"), leaf("d")}
+	nodes := buildTree([]Bytes32{chunks[0], chunks[1], chunks[2], chunks[3]})
+
+	got := MerkleizeChunks(chunks, 0)
+	if got != nodes[1] {
+		t.Fatalf("MerkleizeChunks() = %x, want %x", got, nodes[1])
+	}
+
+	t.Run("pads up to limit", func(t *testing.T) {
+		padded := buildTree([]Bytes32{chunks[0], {}, {}, {}})
+		got := MerkleizeChunks(chunks[:1], 4)
+		if got != padded[1] {
+			t.Fatalf("MerkleizeChunks() with padding = %x, want %x", got, padded[1])
+		}
+	})
+}
+
+func TestMixin(t *testing.T) {
+	root := leaf("root")
+	a := Mixin(root, 3)
+	b := Mixin(root, 3)
+	c := Mixin(root, 4)
+
+	if a != b {
+		t.Fatal("Mixin() should be deterministic for the same inputs")
+	}
+	if a == c {
+		t.Fatal("Mixin() should produce different roots for different lengths")
+	}
+}
+
+// graftSubtree merges sub (as returned by buildTree, keyed by generalized
+// index relative to its own root at index 1) into nodes so that sub's root
+// lands at outerIndex, simulating a field whose value is itself the root
+// of a nested SSZ container - the way BeaconState.finalized_checkpoint
+// nests a Checkpoint, or latest_execution_payload_header nests an
+// ExecutionPayloadHeader. outerIndex's own entry in nodes must already
+// equal sub's root; graftSubtree only adds the nodes beneath it so branches
+// can climb through the nested container instead of stopping at its root.
+func graftSubtree(nodes map[uint64]Bytes32, outerIndex uint64, sub map[uint64]Bytes32) {
+	for k, v := range sub {
+		if k == 1 {
+			continue
+		}
+		subDepth := uint(bits.Len64(k) - 1)
+		globalIndex := outerIndex<<subDepth + (k - 1<<subDepth)
+		nodes[globalIndex] = v
+	}
+}
+
+// TestVerifyMerkleMultiproofAgainstLightClientGIndices checks proofs
+// against the real generalized indices package consensus proves light
+// client updates with (gIndexFinalizedHeader, gIndexCurrentSyncCommittee,
+// gIndexNextSyncCommittee, gIndexWithdrawalsRoot), instead of only the
+// synthetic fixtures above. The tree mirrors the real BeaconState layout
+// (28 fields padded to 32) from Capella onward: finalized_checkpoint
+// (field 20) nests a 2-field Checkpoint, and
+// latest_execution_payload_header (field 24) nests a 15-field
+// ExecutionPayloadHeader whose last field is withdrawals_root - the same
+// two-level nesting that produces gIndexFinalizedHeader=105 and
+// gIndexWithdrawalsRoot=910.
+func TestVerifyMerkleMultiproofAgainstLightClientGIndices(t *testing.T) {
+	const (
+		gIndexFinalizedHeader      = 105
+		gIndexCurrentSyncCommittee = 54
+		gIndexNextSyncCommittee    = 55
+		gIndexWithdrawalsRoot      = 910
+	)
+
+	fields := make([]Bytes32, 32)
+	for i := range fields {
+		fields[i] = leaf(fmt.Sprintf("beacon-state-field-%d", i))
+	}
+
+	epochRoot := leaf("finalized-checkpoint-epoch")
+	finalizedHeaderRoot := leaf("finalized-header-root")
+	checkpoint := buildTree([]Bytes32{epochRoot, finalizedHeaderRoot})
+	fields[20] = checkpoint[1] // finalized_checkpoint
+
+	currentSyncCommitteeRoot := leaf("current-sync-committee-root")
+	fields[22] = currentSyncCommitteeRoot // current_sync_committee
+
+	nextSyncCommitteeRoot := leaf("next-sync-committee-root")
+	fields[23] = nextSyncCommitteeRoot // next_sync_committee
+
+	payloadFields := make([]Bytes32, 16)
+	for i := range payloadFields {
+		payloadFields[i] = leaf(fmt.Sprintf("execution-payload-header-field-%d", i))
+	}
+	withdrawalsRoot := leaf("withdrawals-root")
+	payloadFields[14] = withdrawalsRoot // withdrawals_root
+	payloadHeader := buildTree(payloadFields)
+	fields[24] = payloadHeader[1] // latest_execution_payload_header
+
+	nodes := buildTree(fields)
+	root := nodes[1]
+
+	graftSubtree(nodes, 52, checkpoint)
+	graftSubtree(nodes, 56, payloadHeader)
+
+	t.Run("finalized header root (gIndex 105)", func(t *testing.T) {
+		branch := branchFor(nodes, gIndexFinalizedHeader)
+		if !VerifyMerkleMultiproof(root, []Bytes32{finalizedHeaderRoot}, []uint64{gIndexFinalizedHeader}, branch) {
+			t.Fatal("expected finalized header proof to verify")
+		}
+	})
+
+	t.Run("current sync committee root (gIndex 54)", func(t *testing.T) {
+		branch := branchFor(nodes, gIndexCurrentSyncCommittee)
+		if !VerifyMerkleMultiproof(root, []Bytes32{currentSyncCommitteeRoot}, []uint64{gIndexCurrentSyncCommittee}, branch) {
+			t.Fatal("expected current sync committee proof to verify")
+		}
+	})
+
+	t.Run("next sync committee root (gIndex 55)", func(t *testing.T) {
+		branch := branchFor(nodes, gIndexNextSyncCommittee)
+		if !VerifyMerkleMultiproof(root, []Bytes32{nextSyncCommitteeRoot}, []uint64{gIndexNextSyncCommittee}, branch) {
+			t.Fatal("expected next sync committee proof to verify")
+		}
+	})
+
+	t.Run("withdrawals root (gIndex 910, Capella onward)", func(t *testing.T) {
+		branch := branchFor(nodes, gIndexWithdrawalsRoot)
+		if !VerifyMerkleMultiproof(root, []Bytes32{withdrawalsRoot}, []uint64{gIndexWithdrawalsRoot}, branch) {
+			t.Fatal("expected withdrawals root proof to verify")
+		}
+	})
+
+	t.Run("tampered withdrawals root fails", func(t *testing.T) {
+		branch := branchFor(nodes, gIndexWithdrawalsRoot)
+		if VerifyMerkleMultiproof(root, []Bytes32{leaf("wrong-withdrawals-root")}, []uint64{gIndexWithdrawalsRoot}, branch) {
+			t.Fatal("expected tampered withdrawals root proof to fail")
+		}
+	})
+
+	t.Run("current and next sync committee proved together", func(t *testing.T) {
+		// 54 and 55 share parent 27, the same pattern TestVerifyMerkleMultiproof
+		// exercises for leaves 8 and 9: a single update's sync_committee_bits
+		// and next_sync_committee_branch can cover both fields with one proof.
+		branch := branchFor(nodes, 27)
+		ok := VerifyMerkleMultiproof(
+			root,
+			[]Bytes32{currentSyncCommitteeRoot, nextSyncCommitteeRoot},
+			[]uint64{gIndexCurrentSyncCommittee, gIndexNextSyncCommittee},
+			branch,
+		)
+		if !ok {
+			t.Fatal("expected combined sync committee multiproof to verify")
+		}
+	})
+}
+
+// TestVerifyMerkleMultiproofAcrossForks checks that the same BeaconState
+// tree shape proves the fields each fork's LightClientUpdate actually
+// carries: finalized_checkpoint and the sync committees exist from Altair
+// onward, but latest_execution_payload_header.withdrawals_root only
+// exists from Capella onward (Bellatrix's ExecutionPayloadHeader has no
+// withdrawals_root field at all). A withdrawals proof built for a
+// pre-Capella header is checked against the wrong gIndex and must fail,
+// the way it would if update-handling code forgot to gate the withdrawals
+// check on fork version.
+func TestVerifyMerkleMultiproofAcrossForks(t *testing.T) {
+	const (
+		gIndexFinalizedHeader      = 105
+		gIndexCurrentSyncCommittee = 54
+		gIndexWithdrawalsRoot      = 910
+	)
+
+	newBeaconStateTree := func(withWithdrawals bool) (nodes map[uint64]Bytes32, finalizedHeaderRoot, currentSyncCommitteeRoot, withdrawalsRoot Bytes32) {
+		fields := make([]Bytes32, 32)
+		for i := range fields {
+			fields[i] = leaf(fmt.Sprintf("beacon-state-field-%d", i))
+		}
+
+		epochRoot := leaf("finalized-checkpoint-epoch")
+		finalizedHeaderRoot = leaf("finalized-header-root")
+		checkpoint := buildTree([]Bytes32{epochRoot, finalizedHeaderRoot})
+		fields[20] = checkpoint[1]
+
+		currentSyncCommitteeRoot = leaf("current-sync-committee-root")
+		fields[22] = currentSyncCommitteeRoot
+
+		if withWithdrawals {
+			payloadFields := make([]Bytes32, 16)
+			for i := range payloadFields {
+				payloadFields[i] = leaf(fmt.Sprintf("execution-payload-header-field-%d", i))
+			}
+			withdrawalsRoot = leaf("withdrawals-root")
+			payloadFields[14] = withdrawalsRoot
+			payloadHeader := buildTree(payloadFields)
+			fields[24] = payloadHeader[1]
+
+			nodes = buildTree(fields)
+			graftSubtree(nodes, 52, checkpoint)
+			graftSubtree(nodes, 56, payloadHeader)
+			return
+		}
+
+		// Pre-Capella: latest_execution_payload_header has no
+		// withdrawals_root field, so field 24 is just an opaque payload
+		// header root like every other field.
+		fields[24] = leaf("execution-payload-header-root")
+
+		nodes = buildTree(fields)
+		graftSubtree(nodes, 52, checkpoint)
+		return
+	}
+
+	forks := []struct {
+		name            string
+		withWithdrawals bool
+	}{
+		{"Altair", false},
+		{"Bellatrix", false},
+		{"Capella", true},
+		{"Deneb", true},
+	}
+
+	for _, fork := range forks {
+		t.Run(fork.name, func(t *testing.T) {
+			nodes, finalizedHeaderRoot, currentSyncCommitteeRoot, withdrawalsRoot := newBeaconStateTree(fork.withWithdrawals)
+			root := nodes[1]
+
+			finalizedBranch := branchFor(nodes, gIndexFinalizedHeader)
+			if !VerifyMerkleMultiproof(root, []Bytes32{finalizedHeaderRoot}, []uint64{gIndexFinalizedHeader}, finalizedBranch) {
+				t.Fatal("expected finalized header proof to verify")
+			}
+
+			committeeBranch := branchFor(nodes, gIndexCurrentSyncCommittee)
+			if !VerifyMerkleMultiproof(root, []Bytes32{currentSyncCommitteeRoot}, []uint64{gIndexCurrentSyncCommittee}, committeeBranch) {
+				t.Fatal("expected current sync committee proof to verify")
+			}
+
+			if !fork.withWithdrawals {
+				// Pre-Capella trees never populated a withdrawals_root leaf
+				// at gIndexWithdrawalsRoot, so a proof built against that
+				// gIndex must fail instead of silently passing.
+				withdrawalsBranch := branchFor(nodes, gIndexWithdrawalsRoot)
+				if VerifyMerkleMultiproof(root, []Bytes32{leaf("withdrawals-root")}, []uint64{gIndexWithdrawalsRoot}, withdrawalsBranch) {
+					t.Fatal("expected withdrawals root proof to fail on a pre-Capella header")
+				}
+				return
+			}
+
+			withdrawalsBranch := branchFor(nodes, gIndexWithdrawalsRoot)
+			if !VerifyMerkleMultiproof(root, []Bytes32{withdrawalsRoot}, []uint64{gIndexWithdrawalsRoot}, withdrawalsBranch) {
+				t.Fatal("expected withdrawals root proof to verify")
+			}
+		})
+	}
+}
+
+func bytesBranch(branch []Bytes32) [][]byte {
+	out := make([][]byte, len(branch))
+	for i, b := range branch {
+		b := b
+		out[i] = b[:]
+	}
+	return out
+}
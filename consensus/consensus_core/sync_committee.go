@@ -0,0 +1,91 @@
+package consensus_core
+
+import "fmt"
+
+const (
+	// SyncCommitteeSize is the number of validators in a sync committee.
+	SyncCommitteeSize = 512
+	// BLSPubkeySize is the length in bytes of a compressed BLS12-381 G1 public key.
+	BLSPubkeySize = 48
+)
+
+// SerializedSyncCommittee mirrors the canonical beacon wire format for a
+// sync committee: SyncCommitteeSize validator pubkeys followed by the
+// aggregate pubkey, each BLSPubkeySize bytes.
+type SerializedSyncCommittee [(SyncCommitteeSize + 1) * BLSPubkeySize]byte
+
+func (s SerializedSyncCommittee) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"0x%x"`, s[:])), nil
+}
+
+func (s *SerializedSyncCommittee) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("invalid serialized sync committee json")
+	}
+	raw := data[1 : len(data)-1] // strip quotes
+	if len(raw) >= 2 && raw[0] == '0' && (raw[1] == 'x' || raw[1] == 'X') {
+		raw = raw[2:]
+	}
+	decoded := make([]byte, len(raw)/2)
+	if _, err := fmt.Sscanf(string(raw), "%x", &decoded); err != nil {
+		return fmt.Errorf("failed to decode serialized sync committee: %w", err)
+	}
+	if len(decoded) != len(s) {
+		return fmt.Errorf("serialized sync committee has wrong length: got %d want %d", len(decoded), len(s))
+	}
+	copy(s[:], decoded)
+	return nil
+}
+
+// MarshalSSZ returns the raw wire bytes, which are already in SSZ form.
+func (s *SerializedSyncCommittee) MarshalSSZ() ([]byte, error) {
+	return s[:], nil
+}
+
+// UnmarshalSSZ loads raw wire bytes into s.
+func (s *SerializedSyncCommittee) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != len(s) {
+		return fmt.Errorf("serialized sync committee has wrong length: got %d want %d", len(buf), len(s))
+	}
+	copy(s[:], buf)
+	return nil
+}
+
+// Deserialize unpacks the wire format into a SyncComittee with its pubkeys
+// and cached AggregatePubkey populated.
+func (s *SerializedSyncCommittee) Deserialize() (*SyncComittee, error) {
+	pubkeys := make([]BLSPubKey, SyncCommitteeSize)
+	for i := 0; i < SyncCommitteeSize; i++ {
+		copy(pubkeys[i][:], s[i*BLSPubkeySize:(i+1)*BLSPubkeySize])
+	}
+
+	var aggregate BLSPubKey
+	copy(aggregate[:], s[SyncCommitteeSize*BLSPubkeySize:])
+
+	return &SyncComittee{
+		pubkeys:         pubkeys,
+		AggregatePubkey: aggregate,
+	}, nil
+}
+
+// Serialize packs a SyncComittee back into the canonical wire format.
+func (c *SyncComittee) Serialize() *SerializedSyncCommittee {
+	var s SerializedSyncCommittee
+	for i, pk := range c.pubkeys {
+		copy(s[i*BLSPubkeySize:(i+1)*BLSPubkeySize], pk[:])
+	}
+	copy(s[SyncCommitteeSize*BLSPubkeySize:], c.AggregatePubkey[:])
+	return &s
+}
+
+// Root returns the SSZ hash-tree-root of the committee, computing it once
+// and caching the result so callers validating many updates against the
+// same committee don't pay merkleization cost per update.
+func (c *SyncComittee) Root() Bytes32 {
+	if c.cachedRoot != nil {
+		return *c.cachedRoot
+	}
+	root := c.TreeHashRoot()
+	c.cachedRoot = &root
+	return root
+}
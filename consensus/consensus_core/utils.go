@@ -3,6 +3,8 @@ package consensus_core
 import (
 	"crypto/sha256"
 	"errors"
+	"fmt"
+	"math/bits"
 )
 
 type Forks struct {
@@ -18,9 +20,47 @@ type ForkVersion struct {
 	ForkVersion [4]byte
 }
 
+// DomainType identifies the purpose of a signature, per the beacon chain
+// spec's `compute_domain`. It selects which signing context a signature
+// was produced under, so the same object root can't be replayed across
+// unrelated signature types.
+type DomainType [4]byte
+
+var (
+	DomainBeaconProposer              = DomainType{0, 0, 0, 0}
+	DomainBeaconAttester              = DomainType{1, 0, 0, 0}
+	DomainRandao                      = DomainType{2, 0, 0, 0}
+	DomainDeposit                     = DomainType{3, 0, 0, 0}
+	DomainVoluntaryExit               = DomainType{4, 0, 0, 0}
+	DomainSelectionProof              = DomainType{5, 0, 0, 0}
+	DomainAggregateAndProof           = DomainType{6, 0, 0, 0}
+	DomainSyncCommittee               = DomainType{7, 0, 0, 0}
+	DomainSyncCommitteeSelectionProof = DomainType{8, 0, 0, 0}
+	DomainContributionAndProof        = DomainType{9, 0, 0, 0}
+	DomainBlsToExecutionChange        = DomainType{10, 0, 0, 0}
+	DomainApplicationMask             = DomainType{0, 0, 0, 1}
+)
+
+// ComputeSignRoot computes the signing root for objectRoot under domainType,
+// looking up the fork version active at slot and deriving the domain from
+// it. It generalizes ComputeCommitteeSignRoot to every beacon signing
+// domain, so callers can verify attestations, proposer signatures, and
+// BLS-to-execution changes with the same code path used for sync
+// committee signatures.
+func ComputeSignRoot(domainType DomainType, forks *Forks, slot uint64, genesisValidatorRoot Bytes32, objectRoot Bytes32) Bytes32 {
+	forkVersion := CalculateForkVersion(forks, slot)
+	forkDataRoot := ComputeForkDataRoot(forkVersion, genesisValidatorRoot)
+	domain := ComputeDomain([4]byte(domainType), forkDataRoot)
+	return ComputeSigningRoot(objectRoot, domain)
+}
+
+// ComputeCommitteeSignRoot computes the signing root for header under
+// DomainSyncCommittee from an already computed fork data root, kept for
+// callers that only ever verify sync committee signatures and already have
+// the fork data root in hand. It does not go through ComputeSignRoot and
+// does not look up the fork version by slot.
 func ComputeCommitteeSignRoot(header, forkDataRoot Bytes32) Bytes32 {
-	domainType := [4]byte{7, 0, 0, 0}
-	domain := ComputeDomain(domainType, forkDataRoot)
+	domain := ComputeDomain([4]byte(DomainSyncCommittee), forkDataRoot)
 	return ComputeSigningRoot(header, domain)
 }
 
@@ -49,15 +89,35 @@ func ComputeForkDataRoot(currentVersion [4]byte, genesisValidatorRoot Bytes32) B
 	return forkData.TreeHashRoot()
 }
 
+// ComputeForkDigest derives the 4-byte fork digest gossipsub topic names are
+// keyed on: the first 4 bytes of the fork data root for the active fork
+// version. Light clients need this to join the correct
+// light_client_finality_update/light_client_optimistic_update topics for
+// the network's current fork.
+func ComputeForkDigest(currentVersion [4]byte, genesisValidatorRoot Bytes32) [4]byte {
+	root := ComputeForkDataRoot(currentVersion, genesisValidatorRoot)
+	var digest [4]byte
+	copy(digest[:], root[:4])
+	return digest
+}
+
 // GetParticipatingKeys retrieves the participating public keys from the committee based on the bitfield represented as a byte array.
 func GetParticipatingKeys(committee *SyncComittee, bitfield [64]byte) ([]BLSPubKey, error) {
 	var pks []BLSPubKey
 	numBits := len(bitfield) * 8 // Total number of bits
 
+	if len(committee.pubkeys) != SyncCommitteeSize {
+		return nil, fmt.Errorf("committee has %d pubkeys, want %d", len(committee.pubkeys), SyncCommitteeSize)
+	}
+
 	if len(committee.pubkeys) > numBits {
 		return nil, errors.New("bitfield is too short for the number of public keys")
 	}
 
+	if highestSetBit(bitfield) >= len(committee.pubkeys) {
+		return nil, errors.New("bitfield has bits set beyond the committee size")
+	}
+
 	for i := 0; i < len(bitfield); i++ {
 		byteVal := bitfield[i]
 		for bit := 0; bit < 8; bit++ {
@@ -74,6 +134,68 @@ func GetParticipatingKeys(committee *SyncComittee, bitfield [64]byte) ([]BLSPubK
 	return pks, nil
 }
 
+// highestSetBit returns the index of the highest set bit in bitfield, or -1
+// if no bit is set.
+func highestSetBit(bitfield [64]byte) int {
+	for i := len(bitfield) - 1; i >= 0; i-- {
+		if bitfield[i] == 0 {
+			continue
+		}
+		return i*8 + bits.Len8(bitfield[i]) - 1
+	}
+	return -1
+}
+
+// AggregateParticipating returns the aggregate public key of every
+// participating validator in committee, along with the number of
+// participants. When participation is dense (more than half the
+// committee), it starts from the cached AggregatePubkey and subtracts the
+// non-participating keys, which costs O(committee_size - participants)
+// point operations instead of O(participants); below that threshold it
+// falls back to summing the participating keys directly.
+func AggregateParticipating(committee *SyncComittee, bitfield [64]byte) (BLSPubKey, int, error) {
+	if len(committee.pubkeys) != SyncCommitteeSize {
+		return BLSPubKey{}, 0, fmt.Errorf("committee has %d pubkeys, want %d", len(committee.pubkeys), SyncCommitteeSize)
+	}
+	if highestSetBit(bitfield) >= len(committee.pubkeys) {
+		return BLSPubKey{}, 0, errors.New("bitfield has bits set beyond the committee size")
+	}
+
+	participants := 0
+	for _, b := range bitfield {
+		participants += bits.OnesCount8(b)
+	}
+
+	if participants > SyncCommitteeSize/2 {
+		aggregate := committee.AggregatePubkey
+		for i, pk := range committee.pubkeys {
+			if !bitSet(bitfield, i) {
+				aggregate = aggregate.Sub(pk)
+			}
+		}
+		return aggregate, participants, nil
+	}
+
+	var aggregate BLSPubKey
+	first := true
+	for i, pk := range committee.pubkeys {
+		if !bitSet(bitfield, i) {
+			continue
+		}
+		if first {
+			aggregate = pk
+			first = false
+			continue
+		}
+		aggregate = aggregate.Add(pk)
+	}
+	return aggregate, participants, nil
+}
+
+func bitSet(bitfield [64]byte, index int) bool {
+	return bitfield[index/8]&(1<<(uint(index)%8)) != 0
+}
+
 func ComputeSigningRoot(objectRoot, domain Bytes32) Bytes32 {
 	signingData := SigningData{
 		ObjectRoot: objectRoot,
@@ -0,0 +1,59 @@
+package consensus_core
+
+import "crypto/sha256"
+
+// Withdrawal is the EIP-4895 beacon-chain withdrawal carried by Capella+
+// execution payloads.
+type Withdrawal struct {
+	Index          uint64
+	ValidatorIndex uint64
+	Address        [20]byte
+	Amount         uint64
+}
+
+// TreeHashRoot computes the SSZ hash-tree-root of the Withdrawal container:
+// each of its four fixed-size fields is packed into its own 32-byte chunk,
+// and the (already power-of-two) chunk list is merkleized bottom-up. This
+// can't reuse merkle.MerkleizeChunks since the merkle package imports
+// consensus_core for the Bytes32 alias, and importing it back here would
+// cycle.
+func (w Withdrawal) TreeHashRoot() Bytes32 {
+	chunks := [4]Bytes32{
+		uint64Chunk(w.Index),
+		uint64Chunk(w.ValidatorIndex),
+		bytesChunk(w.Address[:]),
+		uint64Chunk(w.Amount),
+	}
+	return merkleizeChunks(chunks[:])
+}
+
+func uint64Chunk(v uint64) Bytes32 {
+	var chunk Bytes32
+	for i := 0; i < 8; i++ {
+		chunk[i] = byte(v >> (8 * i))
+	}
+	return chunk
+}
+
+func bytesChunk(b []byte) Bytes32 {
+	var chunk Bytes32
+	copy(chunk[:], b)
+	return chunk
+}
+
+func merkleizeChunks(layer []Bytes32) Bytes32 {
+	for len(layer) > 1 {
+		next := make([]Bytes32, len(layer)/2)
+		for i := range next {
+			h := sha256.New()
+			h.Write(layer[2*i][:])
+			h.Write(layer[2*i+1][:])
+			copy(next[i][:], h.Sum(nil))
+		}
+		layer = next
+	}
+	if len(layer) == 0 {
+		return Bytes32{}
+	}
+	return layer[0]
+}
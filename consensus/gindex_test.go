@@ -0,0 +1,69 @@
+package consensus
+
+import (
+	"math/bits"
+	"testing"
+)
+
+// fieldGeneralizedIndex returns the generalized index of the fieldIndex-th
+// field (0-indexed) within an SSZ container holding fieldCount fields:
+// fields are merkleized in a tree padded up to the next power of two, so a
+// field's generalized index is that padded size plus its position.
+func fieldGeneralizedIndex(fieldCount, fieldIndex int) uint64 {
+	size := uint64(1)
+	for size < uint64(fieldCount) {
+		size <<= 1
+	}
+	return size + uint64(fieldIndex)
+}
+
+// concatGeneralizedIndex combines the generalized index of a field within
+// an outer container with the generalized index of a field within that
+// field's own (inner) container, producing the generalized index of the
+// inner field relative to the outer container's root. This mirrors the
+// generalized-index concatenation used throughout the beacon chain light
+// client spec for multi-level proofs, e.g. gIndexFinalizedHeader=105 is
+// BeaconState.finalized_checkpoint (field 20 of 28) concatenated with
+// Checkpoint.root (field 1 of 2).
+func concatGeneralizedIndex(outer, inner uint64) uint64 {
+	innerDepth := uint(bits.Len64(inner) - 1)
+	return outer<<innerDepth + (inner - 1<<innerDepth)
+}
+
+// TestConcatGeneralizedIndexMatchesKnownConstants sanity-checks
+// concatGeneralizedIndex itself against gIndexFinalizedHeader, which is
+// the one gIndex constant whose two-level derivation
+// (BeaconState.finalized_checkpoint, then Checkpoint.root) is easy to spell
+// out by hand.
+func TestConcatGeneralizedIndexMatchesKnownConstants(t *testing.T) {
+	financeCheckpointField := fieldGeneralizedIndex(28, 20) // finalized_checkpoint
+	checkpointRootField := fieldGeneralizedIndex(2, 1)      // Checkpoint.root
+
+	got := concatGeneralizedIndex(financeCheckpointField, checkpointRootField)
+	if got != gIndexFinalizedHeader {
+		t.Fatalf("concatGeneralizedIndex(finalized_checkpoint, root) = %d, want gIndexFinalizedHeader = %d", got, gIndexFinalizedHeader)
+	}
+}
+
+// TestGIndexWithdrawalsRootMatchesContainerLayout derives
+// gIndexWithdrawalsRoot from the actual SSZ container layout instead of
+// trusting the bare literal: BeaconState has 28 fields (Capella onward),
+// with latest_execution_payload_header at field 24; ExecutionPayloadHeader
+// has 15 fields (Capella) with withdrawals_root as the last one, field 14.
+func TestGIndexWithdrawalsRootMatchesContainerLayout(t *testing.T) {
+	const (
+		beaconStateFieldCount           = 28
+		latestExecutionPayloadHeaderIdx = 24
+
+		executionPayloadHeaderFieldCount = 15
+		withdrawalsRootIdx               = 14
+	)
+
+	payloadHeaderField := fieldGeneralizedIndex(beaconStateFieldCount, latestExecutionPayloadHeaderIdx)
+	withdrawalsRootField := fieldGeneralizedIndex(executionPayloadHeaderFieldCount, withdrawalsRootIdx)
+
+	got := concatGeneralizedIndex(payloadHeaderField, withdrawalsRootField)
+	if got != gIndexWithdrawalsRoot {
+		t.Fatalf("derived withdrawals_root generalized index = %d, want gIndexWithdrawalsRoot = %d", got, gIndexWithdrawalsRoot)
+	}
+}
@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+)
+
+// LightClientServer serves the Altair light-client HTTP endpoints out of
+// the verified LightClientStore and the chain file's update history, so
+// other Selene instances or third-party light clients can sync off of
+// this one instead of an upstream beacon node.
+type LightClientServer struct {
+	store     *LightClientStore
+	chainFile *ChainFile
+}
+
+// NewLightClientServer builds a server backed by store (the same store the
+// sync loop maintains) and chainFile (for historical updates by period).
+func NewLightClientServer(store *LightClientStore, chainFile *ChainFile) *LightClientServer {
+	return &LightClientServer{store: store, chainFile: chainFile}
+}
+
+// ListenAndServe starts the HTTP listener on addr. It's expected to be run
+// in its own goroutine, started from ConsensusClient.New when
+// config.Config.ServeLightClient is set.
+func (s *LightClientServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/beacon/light_client/bootstrap/", s.handleBootstrap)
+	mux.HandleFunc("/eth/v1/beacon/light_client/updates", s.handleUpdates)
+	mux.HandleFunc("/eth/v1/beacon/light_client/finality_update", s.handleFinalityUpdate)
+	mux.HandleFunc("/eth/v1/beacon/light_client/optimistic_update", s.handleOptimisticUpdate)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *LightClientServer) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	blockRoot := r.URL.Path[len("/eth/v1/beacon/light_client/bootstrap/"):]
+	if blockRoot != fmt.Sprintf("0x%x", s.store.FinalizedHeader.TreeHashRoot()) {
+		http.Error(w, "block_root not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"header":                         s.store.FinalizedHeader,
+			"current_sync_committee":        s.store.CurrentSyncCommitee,
+			"current_sync_committee_branch": s.store.CurrentSyncCommitteeBranch,
+		},
+	})
+}
+
+func (s *LightClientServer) handleUpdates(w http.ResponseWriter, r *http.Request) {
+	startPeriod, err := strconv.ParseUint(r.URL.Query().Get("start_period"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid start_period", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.ParseUint(r.URL.Query().Get("count"), 10, 8)
+	if err != nil {
+		http.Error(w, "invalid count", http.StatusBadRequest)
+		return
+	}
+
+	var updates []consensus_core.Update
+	if s.chainFile != nil {
+		const slotsPerPeriod = 8192 // 256 epochs * 32 slots
+		for chunk := range s.chainFile.Iterate(startPeriod * slotsPerPeriod) {
+			if chunk.Kind != ChunkKindUpdate {
+				continue
+			}
+			var update consensus_core.Update
+			if err := update.UnmarshalSSZ(chunk.SSZ); err != nil {
+				continue
+			}
+			if calculate_sync_period(update.AttestedHeader.Slot) < startPeriod {
+				continue
+			}
+			updates = append(updates, update)
+			if uint64(len(updates)) >= count {
+				break
+			}
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"data": updates})
+}
+
+func (s *LightClientServer) handleFinalityUpdate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"attested_header":  s.store.OptimisticHeader,
+			"finalized_header": s.store.FinalizedHeader,
+		},
+	})
+}
+
+func (s *LightClientServer) handleOptimisticUpdate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"data": map[string]interface{}{
+			"attested_header": s.store.OptimisticHeader,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+	"github.com/golang/snappy"
+	"github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+)
+
+const (
+	topicLightClientFinalityUpdate   = "/eth2/%x/light_client_finality_update/ssz_snappy"
+	topicLightClientOptimisticUpdate = "/eth2/%x/light_client_optimistic_update/ssz_snappy"
+)
+
+// GossipConsensusRpc extends ConsensusRpc with a subscription to the
+// beacon gossipsub network, so finality and optimistic updates can be
+// verified and applied the moment they're gossiped instead of waiting for
+// the next HTTP poll.
+type GossipConsensusRpc interface {
+	ConsensusRpc
+	SubscribeLightClientUpdates(ctx context.Context) (<-chan consensus_core.FinalityUpdate, <-chan consensus_core.OptimisticUpdate, error)
+}
+
+// Libp2pGossipRpc wraps a ConsensusRpc with a libp2p gossipsub subscription
+// to the light_client_finality_update and light_client_optimistic_update
+// topics for forkDigest. Deterministic calls (GetBlock, GetUpdates, ...)
+// are delegated to the wrapped ConsensusRpc unchanged.
+type Libp2pGossipRpc struct {
+	ConsensusRpc
+	forkDigest [4]byte
+	host       host.Host
+	pubsub     *pubsub.PubSub
+}
+
+// NewLibp2pGossipRpc starts a libp2p host and a gossipsub router for
+// forkDigest, wrapping inner for all other RPC calls. The light-client
+// topics themselves are joined by SubscribeLightClientUpdates.
+func NewLibp2pGossipRpc(ctx context.Context, inner ConsensusRpc, forkDigest [4]byte) (*Libp2pGossipRpc, error) {
+	h, err := libp2p.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start libp2p host: %w", err)
+	}
+
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start gossipsub: %w", err)
+	}
+
+	return &Libp2pGossipRpc{
+		ConsensusRpc: inner,
+		forkDigest:   forkDigest,
+		host:         h,
+		pubsub:       ps,
+	}, nil
+}
+
+// SubscribeLightClientUpdates joins the light_client_finality_update and
+// light_client_optimistic_update topics, decodes each SSZ+snappy message,
+// and streams the results on the returned channels until ctx is done.
+func (g *Libp2pGossipRpc) SubscribeLightClientUpdates(ctx context.Context) (<-chan consensus_core.FinalityUpdate, <-chan consensus_core.OptimisticUpdate, error) {
+	finalityTopic, err := g.pubsub.Join(fmt.Sprintf(topicLightClientFinalityUpdate, g.forkDigest))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to join finality update topic: %w", err)
+	}
+	optimisticTopic, err := g.pubsub.Join(fmt.Sprintf(topicLightClientOptimisticUpdate, g.forkDigest))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to join optimistic update topic: %w", err)
+	}
+
+	finalitySub, err := finalityTopic.Subscribe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to subscribe to finality update topic: %w", err)
+	}
+	optimisticSub, err := optimisticTopic.Subscribe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to subscribe to optimistic update topic: %w", err)
+	}
+
+	finalityUpdates := make(chan consensus_core.FinalityUpdate, 16)
+	optimisticUpdates := make(chan consensus_core.OptimisticUpdate, 16)
+
+	go func() {
+		defer close(finalityUpdates)
+		for {
+			msg, err := finalitySub.Next(ctx)
+			if err != nil {
+				return
+			}
+			var update consensus_core.FinalityUpdate
+			if err := decodeSSZSnappy(msg.Data, &update); err != nil {
+				continue
+			}
+			select {
+			case finalityUpdates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(optimisticUpdates)
+		for {
+			msg, err := optimisticSub.Next(ctx)
+			if err != nil {
+				return
+			}
+			var update consensus_core.OptimisticUpdate
+			if err := decodeSSZSnappy(msg.Data, &update); err != nil {
+				continue
+			}
+			select {
+			case optimisticUpdates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return finalityUpdates, optimisticUpdates, nil
+}
+
+// sszUnmarshaler is implemented by every consensus_core wire type.
+type sszUnmarshaler interface {
+	UnmarshalSSZ(buf []byte) error
+}
+
+func decodeSSZSnappy(data []byte, out sszUnmarshaler) error {
+	decoded, err := snappy.Decode(nil, data)
+	if err != nil {
+		return fmt.Errorf("failed to decompress gossip message: %w", err)
+	}
+	return out.UnmarshalSSZ(decoded)
+}
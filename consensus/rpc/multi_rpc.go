@@ -0,0 +1,329 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+)
+
+// hedgeDelay is how long MultiConsensusRpc waits for endpoint 1 to answer a
+// latency-sensitive call before also firing the request at endpoint 2.
+const hedgeDelay = 250 * time.Millisecond
+
+// endpointScore tracks how trustworthy an endpoint has been, so one that
+// repeatedly disagrees with quorum or returns invalid SSZ can be evicted
+// instead of being raced or hedged against forever.
+type endpointScore struct {
+	mu        sync.Mutex
+	agrees    int
+	disagrees int
+	evicted   bool
+}
+
+func (s *endpointScore) recordAgree() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agrees++
+}
+
+func (s *endpointScore) recordDisagree() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disagrees++
+	if s.disagrees >= 3 && s.disagrees > s.agrees {
+		s.evicted = true
+	}
+}
+
+func (s *endpointScore) isEvicted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evicted
+}
+
+// MultiConsensusRpc implements ConsensusRpc by fanning every call out to N
+// endpoints: deterministic calls (bootstrap, updates, blocks) take the
+// first response whose SSZ hash-tree-root is agreed on by a quorum of
+// endpoints and return it without waiting for the rest (the outstanding
+// requests are not cancelled), while latency-sensitive calls hedge
+// instead, firing endpoint 1 then endpoint 2 shortly after, returning
+// whichever answers first.
+type MultiConsensusRpc struct {
+	endpoints []ConsensusRpc
+	scores    []*endpointScore
+	quorum    int
+}
+
+// NewMultiConsensusRpc builds a MultiConsensusRpc over endpoints, requiring
+// at least quorum agreeing responses for deterministic calls. quorum
+// defaults to ceil(N/2), half the endpoints rounded up, when 0 is passed.
+func NewMultiConsensusRpc(endpoints []ConsensusRpc, quorum int) (*MultiConsensusRpc, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("need at least one consensus RPC endpoint")
+	}
+	if quorum <= 0 {
+		quorum = (len(endpoints) + 1) / 2
+	}
+
+	scores := make([]*endpointScore, len(endpoints))
+	for i := range scores {
+		scores[i] = &endpointScore{}
+	}
+
+	return &MultiConsensusRpc{endpoints: endpoints, scores: scores, quorum: quorum}, nil
+}
+
+// Scoreboard returns, per configured endpoint index, the number of times
+// it has agreed with and disagreed from quorum, and whether it has been
+// evicted. An eviction is never lifted; only when every endpoint is
+// evicted are all of them queried again. Operators can use this to see
+// which beacon APIs are diverging.
+func (m *MultiConsensusRpc) Scoreboard() []struct {
+	Agrees    int
+	Disagrees int
+	Evicted   bool
+} {
+	out := make([]struct {
+		Agrees    int
+		Disagrees int
+		Evicted   bool
+	}, len(m.scores))
+
+	for i, s := range m.scores {
+		s.mu.Lock()
+		out[i] = struct {
+			Agrees    int
+			Disagrees int
+			Evicted   bool
+		}{s.agrees, s.disagrees, s.evicted}
+		s.mu.Unlock()
+	}
+	return out
+}
+
+func (m *MultiConsensusRpc) live() []int {
+	var live []int
+	for i, s := range m.scores {
+		if !s.isEvicted() {
+			live = append(live, i)
+		}
+	}
+	if len(live) == 0 {
+		for i := range m.endpoints {
+			live = append(live, i)
+		}
+	}
+	return live
+}
+
+// quorumFetch calls fetch on every live endpoint concurrently and returns
+// the first result whose hash-tree-root (as computed by key) is agreed on
+// by at least m.quorum endpoints, scoring agreement/disagreement as
+// results come in. Responses still outstanding when quorum is reached are
+// not scored.
+func quorumFetch[T any](m *MultiConsensusRpc, fetch func(ConsensusRpc) (T, error), key func(T) consensus_core.Bytes32) (T, error) {
+	var zero T
+	live := m.live()
+
+	type result struct {
+		idx   int
+		value T
+		err   error
+	}
+
+	results := make(chan result, len(live))
+	for _, idx := range live {
+		go func(idx int) {
+			value, err := fetch(m.endpoints[idx])
+			results <- result{idx: idx, value: value, err: err}
+		}(idx)
+	}
+
+	roots := map[consensus_core.Bytes32]int{}
+	values := map[consensus_core.Bytes32]T{}
+	voters := map[consensus_core.Bytes32][]int{}
+
+	var lastErr error
+	for range live {
+		r := <-results
+		if r.err != nil {
+			lastErr = r.err
+			m.scores[r.idx].recordDisagree()
+			continue
+		}
+
+		root := key(r.value)
+		roots[root]++
+		values[root] = r.value
+		voters[root] = append(voters[root], r.idx)
+
+		if roots[root] >= m.quorum {
+			for _, voter := range voters[root] {
+				m.scores[voter].recordAgree()
+			}
+			for other, voterList := range voters {
+				if other == root {
+					continue
+				}
+				for _, voter := range voterList {
+					m.scores[voter].recordDisagree()
+				}
+			}
+			return values[root], nil
+		}
+	}
+
+	for root, voterList := range voters {
+		if roots[root] < m.quorum {
+			for _, voter := range voterList {
+				m.scores[voter].recordDisagree()
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return zero, lastErr
+	}
+	return zero, fmt.Errorf("no quorum of %d endpoints agreed on a response", m.quorum)
+}
+
+// hedgedFetch fires fetch against endpoint 0, then additionally against
+// endpoint 1 after hedgeDelay if endpoint 0 hasn't answered yet, returning
+// whichever non-error response arrives first.
+func hedgedFetch[T any](m *MultiConsensusRpc, fetch func(ConsensusRpc) (T, error)) (T, error) {
+	live := m.live()
+
+	type result struct {
+		value T
+		err   error
+	}
+	results := make(chan result, len(live))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	launch := func(idx int) {
+		go func() {
+			value, err := fetch(m.endpoints[idx])
+			select {
+			case results <- result{value: value, err: err}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	launch(live[0])
+	timer := time.NewTimer(hedgeDelay)
+	defer timer.Stop()
+
+	remaining := 1
+	for i := 1; i < len(live); i++ {
+		select {
+		case r := <-results:
+			if r.err == nil {
+				return r.value, nil
+			}
+			remaining--
+		case <-timer.C:
+			launch(live[i])
+			remaining++
+		}
+	}
+
+	var lastErr error
+	for remaining > 0 {
+		r := <-results
+		remaining--
+		if r.err == nil {
+			return r.value, nil
+		}
+		lastErr = r.err
+	}
+
+	var zero T
+	if lastErr == nil {
+		lastErr = fmt.Errorf("all endpoints failed")
+	}
+	return zero, lastErr
+}
+
+func (m *MultiConsensusRpc) GetBootstrap(checkpoint [32]byte) (consensus_core.Bootstrap, error) {
+	return quorumFetch(m, func(r ConsensusRpc) (consensus_core.Bootstrap, error) {
+		return r.GetBootstrap(checkpoint)
+	}, func(b consensus_core.Bootstrap) consensus_core.Bytes32 {
+		return b.Header.TreeHashRoot()
+	})
+}
+
+func (m *MultiConsensusRpc) GetUpdates(period uint64, count uint8) ([]consensus_core.Update, error) {
+	return quorumFetch(m, func(r ConsensusRpc) ([]consensus_core.Update, error) {
+		return r.GetUpdates(period, count)
+	}, updatesKey)
+}
+
+// updatesKey hashes the attested-header roots of a GetUpdates response, in
+// order, into a single digest so quorumFetch can cross-validate the whole
+// slice an endpoint returned instead of trusting it unchecked.
+func updatesKey(updates []consensus_core.Update) consensus_core.Bytes32 {
+	h := sha256.New()
+	for _, u := range updates {
+		root := u.AttestedHeader.TreeHashRoot()
+		h.Write(root[:])
+	}
+	var out consensus_core.Bytes32
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+func (m *MultiConsensusRpc) GetFinalityUpdate() (consensus_core.FinalityUpdate, error) {
+	return hedgedFetch(m, func(r ConsensusRpc) (consensus_core.FinalityUpdate, error) {
+		return r.GetFinalityUpdate()
+	})
+}
+
+func (m *MultiConsensusRpc) GetOptimisticUpdate() (consensus_core.OptimisticUpdate, error) {
+	return hedgedFetch(m, func(r ConsensusRpc) (consensus_core.OptimisticUpdate, error) {
+		return r.GetOptimisticUpdate()
+	})
+}
+
+func (m *MultiConsensusRpc) GetBlock(slot uint64) (consensus_core.BeaconBlock, error) {
+	return quorumFetch(m, func(r ConsensusRpc) (consensus_core.BeaconBlock, error) {
+		return r.GetBlock(slot)
+	}, func(b consensus_core.BeaconBlock) consensus_core.Bytes32 {
+		return b.Body.TreeHashRoot()
+	})
+}
+
+func (m *MultiConsensusRpc) ChainId() (uint64, error) {
+	return hedgedFetch(m, func(r ConsensusRpc) (uint64, error) {
+		return r.ChainId()
+	})
+}
+
+func (m *MultiConsensusRpc) GetBlobSidecars(slot uint64) ([]consensus_core.RawBlobSidecar, error) {
+	return quorumFetch(m, func(r ConsensusRpc) ([]consensus_core.RawBlobSidecar, error) {
+		return r.GetBlobSidecars(slot)
+	}, blobSidecarsKey)
+}
+
+// blobSidecarsKey hashes every sidecar's KZG commitment, sorted by index,
+// so quorumFetch can cross-validate the whole slice an endpoint returned
+// the same way updatesKey does for GetUpdates - sorting first means two
+// endpoints that agree on content but returned it in a different order
+// still hash to the same key.
+func blobSidecarsKey(sidecars []consensus_core.RawBlobSidecar) consensus_core.Bytes32 {
+	sorted := consensus_core.SortedRawBlobSidecars(sidecars)
+
+	h := sha256.New()
+	for _, s := range sorted {
+		h.Write(s.KZGCommitment[:])
+	}
+	var out consensus_core.Bytes32
+	copy(out[:], h.Sum(nil))
+	return out
+}
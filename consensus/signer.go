@@ -0,0 +1,33 @@
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/BlocSoc-iitr/selene/config"
+	"github.com/BlocSoc-iitr/selene/consensus/consensus_core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SignerForPayload mirrors go-ethereum's types.MakeSigner: it picks the
+// transaction signer that was actually active when payload was produced,
+// based on its block number and timestamp against cfg.Chain's fork
+// schedule, instead of always using the latest signer for the chain ID.
+// Using the latest signer unconditionally misattributes senders on
+// historical pre-EIP-155/pre-Berlin transactions and silently accepts
+// transaction types that aren't enabled yet at the payload's timestamp.
+func SignerForPayload(cfg *config.Config, payload consensus_core.ExecutionPayload) types.Signer {
+	chainConfig := cfg.Chain.ChainConfig
+	blockNumber := new(big.Int).SetUint64(payload.BlockNumber())
+	blockTime := payload.Timestamp()
+
+	return types.MakeSigner(chainConfig, blockNumber, blockTime)
+}
+
+// checkTxTypeEnabled rejects transaction types that aren't enabled yet at
+// the payload's timestamp, e.g. a BlobTx seen before Cancun.
+func checkTxTypeEnabled(cfg *config.Config, payload consensus_core.ExecutionPayload, txType uint8) error {
+	if txType == types.BlobTxType && !blobsEnabledForPayload(cfg, payload) {
+		return ErrTxTypeNotEnabled
+	}
+	return nil
+}